@@ -0,0 +1,255 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/util"
+)
+
+// textContext describes where inline text is written in the output, which determines the characters
+// that must be escaped for the text to keep its meaning when the output is parsed again.
+type textContext struct {
+	// lineStart is true if the text starts a line that could be interpreted as the start of a block.
+	lineStart bool
+	// continuation is true if the line started by the text is not the first line of its block. Fewer
+	// kinds of blocks can interrupt a paragraph than can start one.
+	continuation bool
+	// lineEnd is true if the text ends its line.
+	lineEnd bool
+	// before and after are the characters adjacent to the text, which determine whether delimiter
+	// runs in the text can open or close emphasis. after is utf8.RuneError when unknown.
+	before, after rune
+}
+
+// entityPattern matches the start of an HTML entity or numeric character reference.
+var entityPattern = regexp.MustCompile(`^&(?:#[xX][0-9a-fA-F]{1,6}|#[0-9]{1,7}|[A-Za-z][A-Za-z0-9]{1,31});`)
+
+// escapeInline inserts the backslash escapes needed for text to be parsed as plain text rather than
+// as inline markup. If literal is true, backslashes and entity references in the text are escaped as
+// well. Otherwise they are assumed to be escapes already present in the text.
+func escapeInline(text []byte, ctx textContext, literal bool) []byte {
+	result := make([]byte, 0, len(text))
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch c {
+		case '\\':
+			if i+1 < len(text) && util.IsPunct(text[i+1]) {
+				if literal {
+					result = append(result, '\\')
+				} else {
+					// Keep existing escape sequences intact
+					result = append(result, c, text[i+1])
+					i++
+					continue
+				}
+			} else if i+1 == len(text) {
+				// A trailing backslash could escape the next character or become a hard line break
+				result = append(result, '\\')
+			}
+		case '&':
+			if literal && entityPattern.Match(text[i:]) {
+				result = append(result, '\\')
+			}
+		case '`', '[', ']':
+			result = append(result, '\\')
+		case '<':
+			if i+1 < len(text) {
+				if next := text[i+1]; util.IsAlphaNumeric(next) || next == '/' || next == '!' || next == '?' {
+					result = append(result, '\\')
+				}
+			} else if !ctx.lineEnd {
+				result = append(result, '\\')
+			}
+		case '*', '_', '~':
+			j := i
+			for j < len(text) && text[j] == c {
+				j++
+			}
+			before := ctx.before
+			if i > 0 {
+				before, _ = utf8.DecodeLastRune(text[:i])
+			}
+			after := ctx.after
+			if j < len(text) {
+				after, _ = utf8.DecodeRune(text[j:])
+			}
+			escape := canDelimit(c, before, after)
+			if after == utf8.RuneError {
+				// The following character is unknown, so assume the worst.
+				escape = canDelimit(c, before, 'a') || canDelimit(c, before, '.')
+			}
+			// Strikethrough is limited to runs of one or two tildes
+			if c == '~' && j-i > 2 {
+				escape = false
+			}
+			for ; i < j; i++ {
+				if escape {
+					result = append(result, '\\')
+				}
+				result = append(result, c)
+			}
+			i--
+			continue
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// canDelimit returns true if a run of the given delimiter character between before and after can
+// open or close emphasis, following the CommonMark rules for left- and right-flanking runs.
+func canDelimit(c byte, before, after rune) bool {
+	if c == '_' {
+		return canOpenUnderscore(before, after) || canCloseUnderscore(before, after)
+	}
+	isLeft, isRight := flanking(before, after)
+	return isLeft || isRight
+}
+
+// canOpenUnderscore returns true if a run of underscores between before and after can open
+// emphasis. Unlike asterisks, underscores can't open intraword emphasis.
+func canOpenUnderscore(before, after rune) bool {
+	isLeft, isRight := flanking(before, after)
+	return isLeft && (!isRight || util.IsPunctRune(before))
+}
+
+// canCloseUnderscore returns true if a run of underscores between before and after can close
+// emphasis.
+func canCloseUnderscore(before, after rune) bool {
+	isLeft, isRight := flanking(before, after)
+	return isRight && (!isLeft || util.IsPunctRune(after))
+}
+
+// flanking returns whether a delimiter run between before and after is left-flanking and
+// right-flanking.
+func flanking(before, after rune) (isLeft, isRight bool) {
+	beforeIsPunctuation := util.IsPunctRune(before)
+	beforeIsWhitespace := util.IsSpaceRune(before)
+	afterIsPunctuation := util.IsPunctRune(after)
+	afterIsWhitespace := util.IsSpaceRune(after)
+
+	isLeft = !afterIsWhitespace && (!afterIsPunctuation || beforeIsWhitespace || beforeIsPunctuation)
+	isRight = !beforeIsWhitespace && (!beforeIsPunctuation || afterIsWhitespace || afterIsPunctuation)
+	return isLeft, isRight
+}
+
+// escapeLineStart inserts a backslash escape if the text starts a line and begins with a marker that
+// would start a new block, such as a heading, blockquote, list item, or thematic break.
+func escapeLineStart(text []byte, ctx textContext) []byte {
+	if !ctx.lineStart {
+		return text
+	}
+	// Up to three spaces of indentation are ignored by block markers
+	start := 0
+	for start < len(text) && start < 3 && text[start] == ' ' {
+		start++
+	}
+	line := text[start:]
+	if len(line) == 0 {
+		return text
+	}
+	// pos is the position at which to insert the escape, if any
+	pos := -1
+	switch c := line[0]; c {
+	case '>':
+		pos = start
+	case '#':
+		n := runLength(line, c)
+		if n <= 6 && hasSpaceOrLineEnd(line[n:], ctx) {
+			pos = start
+		}
+	case '`', '~':
+		// The info string of a backtick fence cannot contain backticks
+		n := runLength(line, c)
+		if n >= 3 && (c == '~' || (bytes.IndexByte(line[n:], '`') < 0 && ctx.after != '`')) {
+			pos = start
+		}
+	case '-', '+', '*', '_', '=':
+		switch {
+		case ctx.lineEnd && isThematicBreak(line):
+			pos = start
+		case ctx.lineEnd && ctx.continuation && isSetextUnderline(line):
+			pos = start
+		case c != '_' && c != '=' && hasSpaceOrLineEnd(line[1:], ctx):
+			// An empty list item cannot interrupt a paragraph
+			if !ctx.continuation || hasContent(line[1:], ctx) {
+				pos = start
+			}
+		}
+	case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		n := 0
+		for n < len(line) && n < 10 && line[n] >= '0' && line[n] <= '9' {
+			n++
+		}
+		if n > 9 || n == len(line) || (line[n] != '.' && line[n] != ')') || !hasSpaceOrLineEnd(line[n+1:], ctx) {
+			break
+		}
+		// Only a list starting with 1 can interrupt a paragraph, and only if it isn't empty
+		if !ctx.continuation || (bytes.Equal(bytes.TrimLeft(line[:n], "0"), []byte("1")) && hasContent(line[n+1:], ctx)) {
+			// Escape the delimiter rather than the number
+			pos = start + n
+		}
+	}
+	if pos < 0 {
+		return text
+	}
+	result := make([]byte, 0, len(text)+1)
+	result = append(result, text[:pos]...)
+	result = append(result, '\\')
+	return append(result, text[pos:]...)
+}
+
+// runLength returns the number of times c is repeated at the start of line.
+func runLength(line []byte, c byte) int {
+	n := 0
+	for n < len(line) && line[n] == c {
+		n++
+	}
+	return n
+}
+
+// hasSpaceOrLineEnd returns true if rest starts with a space or tab, or is empty and ends the line.
+func hasSpaceOrLineEnd(rest []byte, ctx textContext) bool {
+	if len(rest) == 0 {
+		return ctx.lineEnd
+	}
+	return rest[0] == ' ' || rest[0] == '\t'
+}
+
+// hasContent returns true if the rest of the line contains anything other than whitespace.
+func hasContent(rest []byte, ctx textContext) bool {
+	return !ctx.lineEnd || !util.IsBlank(rest)
+}
+
+// isThematicBreak returns true if line consists of three or more matching '-', '*', or '_'
+// characters and optional spaces or tabs.
+func isThematicBreak(line []byte) bool {
+	c := line[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	count := 0
+	for _, b := range line {
+		switch b {
+		case c:
+			count++
+		case ' ', '\t':
+		default:
+			return false
+		}
+	}
+	return count >= 3
+}
+
+// isSetextUnderline returns true if line consists of '=' or '-' characters followed by optional
+// spaces or tabs.
+func isSetextUnderline(line []byte) bool {
+	c := line[0]
+	if c != '=' && c != '-' {
+		return false
+	}
+	n := runLength(line, c)
+	return util.IsBlank(line[n:])
+}
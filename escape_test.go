@@ -0,0 +1,111 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// TestEscapeInline tests that inline markup characters are escaped only where they would be parsed
+// as markup.
+func TestEscapeInline(t *testing.T) {
+	unknown := textContext{before: '\n', after: utf8.RuneError}
+	lineEnd := textContext{before: '\n', after: '\n', lineEnd: true}
+	testCases := []struct {
+		name     string
+		text     string
+		ctx      textContext
+		literal  bool
+		expected string
+	}{
+		{"Plain text", "foo bar", lineEnd, false, "foo bar"},
+		{"Emphasis", "*foo*", lineEnd, false, "\\*foo\\*"},
+		{"Strong", "__foo__", lineEnd, false, "\\_\\_foo\\_\\_"},
+		{"Surrounded by spaces", "2 * 3 _ 4", lineEnd, false, "2 * 3 _ 4"},
+		{"Intraword underscore", "snake_case_name", lineEnd, false, "snake_case_name"},
+		{"Intraword asterisk", "2*3", lineEnd, false, "2\\*3"},
+		{"Unknown following character", "foo_", unknown, false, "foo\\_"},
+		{"Strikethrough", "~foo~ ~~~", lineEnd, false, "\\~foo\\~ ~~~"},
+		{"Code span", "`foo`", lineEnd, false, "\\`foo\\`"},
+		{"Link", "[foo](bar)", lineEnd, false, "\\[foo\\](bar)"},
+		{"Raw HTML", "<a> < b", lineEnd, false, "\\<a> < b"},
+		{"Existing escapes", "\\*foo\\* \\ bar", lineEnd, false, "\\*foo\\* \\ bar"},
+		{"Literal backslashes", "\\*foo\\* \\ bar", lineEnd, true, "\\\\\\*foo\\\\\\* \\ bar"},
+		{"Trailing backslash", "foo\\", lineEnd, false, "foo\\\\"},
+		{"Entity", "&amp; & &#35;", lineEnd, false, "&amp; & &#35;"},
+		{"Literal entity", "&amp; & &#35;", lineEnd, true, "\\&amp; & \\&#35;"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := escapeInline([]byte(tc.text), tc.ctx, tc.literal)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+// TestEscapeLineStart tests that block markers at the start of a line are escaped only where they
+// would start a new block.
+func TestEscapeLineStart(t *testing.T) {
+	first := textContext{lineStart: true, lineEnd: true}
+	continuation := textContext{lineStart: true, continuation: true, lineEnd: true}
+	testCases := []struct {
+		name     string
+		text     string
+		ctx      textContext
+		expected string
+	}{
+		{"Not at line start", "# foo", textContext{lineEnd: true}, "# foo"},
+		{"ATX heading", "# foo", continuation, "\\# foo"},
+		{"Not an ATX heading", "#foo ####### bar", first, "#foo ####### bar"},
+		{"Blockquote", ">foo", continuation, "\\>foo"},
+		{"Bullet list", "- foo", first, "\\- foo"},
+		{"Empty bullet list", "+", first, "\\+"},
+		{"Empty bullet list continuation", "+", continuation, "+"},
+		{"Ordered list", "2. foo", first, "2\\. foo"},
+		{"Ordered list continuation", "2) foo", continuation, "2) foo"},
+		{"Ordered list continuation starting at one", "1) foo", continuation, "1\\) foo"},
+		{"Too many digits", "1234567890. foo", first, "1234567890. foo"},
+		{"Thematic break", "* * *", continuation, "\\* * *"},
+		{"Setext underline", "===", continuation, "\\==="},
+		{"Setext underline first line", "===", first, "==="},
+		{"Code fence", "~~~ foo", first, "\\~~~ foo"},
+		{"Not a code fence", "```foo`", first, "```foo`"},
+		{"Indented marker", "  > foo", first, "  \\> foo"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := escapeLineStart([]byte(tc.text), tc.ctx)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+// TestRenderEscapedText tests that text added by AST transformers keeps its meaning when rendered.
+func TestRenderEscapedText(t *testing.T) {
+	source := []byte("foo")
+	doc := ast.NewDocument()
+	paragraph := ast.NewParagraph()
+	doc.AppendChild(doc, paragraph)
+	first := ast.NewTextSegment(text.NewSegment(0, len(source)))
+	first.SetSoftLineBreak(true)
+	paragraph.AppendChild(paragraph, first)
+	second := ast.NewTextSegment(text.NewSegment(0, len(source)))
+	second.SetSoftLineBreak(true)
+	paragraph.AppendChild(paragraph, ast.NewString([]byte("1. *not a list*")))
+	paragraph.AppendChild(paragraph, second)
+	paragraph.AppendChild(paragraph, ast.NewString([]byte("> not a quote")))
+	code := ast.NewString([]byte("*code*"))
+	code.SetCode(true)
+	paragraph.AppendChild(paragraph, code)
+
+	buf := bytes.Buffer{}
+	md := goldmark.New(goldmark.WithRenderer(NewRenderer()))
+	err := md.Renderer().Render(&buf, source, doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n1\\. \\*not a list\\*foo\n\\> not a quote*code*\n", buf.String())
+}
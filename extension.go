@@ -15,7 +15,10 @@ func NewExtension(opts ...Option) *rendererExtension {
 // Extend implements goldmark.Extension.Extend
 func (re *rendererExtension) Extend(md goldmark.Markdown) {
 	renderer := NewRenderer(re.opts...)
+	renderer.parser = md.Parser()
 	md.SetRenderer(renderer)
+	// Keep reference links and link reference definitions in the AST so they can be rendered
+	md.Parser().AddOptions(linkReferenceParserOptions()...)
 	if renderer.config.TypographerSubstitutions {
 		enableTypographicSubstitutions(md)
 	} else {
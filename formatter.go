@@ -0,0 +1,146 @@
+package markdown
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/text"
+)
+
+// A CodeFormatter formats the code of fenced code blocks.
+type CodeFormatter interface {
+	// FormatCode returns the formatted code, or an error if the code can't be formatted.
+	FormatCode(code []byte) ([]byte, error)
+}
+
+// CodeFormatterFunc is an adapter to use ordinary functions as CodeFormatters.
+type CodeFormatterFunc func(code []byte) ([]byte, error)
+
+// FormatCode implements CodeFormatter.FormatCode
+func (f CodeFormatterFunc) FormatCode(code []byte) ([]byte, error) {
+	return f(code)
+}
+
+// GoCodeFormatter formats Go code with go/format.
+var GoCodeFormatter CodeFormatter = CodeFormatterFunc(format.Source)
+
+// JSONCodeFormatter indents JSON code by two spaces per level.
+var JSONCodeFormatter CodeFormatter = CodeFormatterFunc(func(code []byte) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := json.Indent(&buf, bytes.TrimSpace(code), "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte(lineDelim)
+	return buf.Bytes(), nil
+})
+
+// MarkdownCodeFormatter formats markdown code with the renderer that renders the code block, so
+// that it is formatted with the same options as the rest of the document.
+var MarkdownCodeFormatter CodeFormatter = markdownCodeFormatter{}
+
+type markdownCodeFormatter struct{}
+
+// FormatCode implements CodeFormatter.FormatCode. Used on its own, the formatter formats code with
+// the default options.
+func (markdownCodeFormatter) FormatCode(code []byte) ([]byte, error) {
+	return NewRenderer().formatMarkdown(code)
+}
+
+// DefaultCodeFormatters returns the formatters built into this package, keyed by the languages
+// they format.
+func DefaultCodeFormatters() CodeFormatters {
+	return CodeFormatters{
+		"go":       GoCodeFormatter,
+		"json":     JSONCodeFormatter,
+		"markdown": MarkdownCodeFormatter,
+		"md":       MarkdownCodeFormatter,
+	}
+}
+
+// A CodeFormatError is reported to the diagnostic handler when a fenced code block can't be
+// formatted. The code block is rendered as it is.
+type CodeFormatError struct {
+	// Language is the language of the code block, taken from its info string
+	Language string
+	// Line is the line number of the first line of code in the source, starting at 1
+	Line int
+	// Err is the error returned by the code formatter
+	Err error
+}
+
+// Error implements error.Error
+func (e *CodeFormatError) Error() string {
+	return fmt.Sprintf("line %d: formatting %s code: %v", e.Line, e.Language, e.Err)
+}
+
+// Unwrap returns the error returned by the code formatter.
+func (e *CodeFormatError) Unwrap() error {
+	return e.Err
+}
+
+// formatCode returns the code of the given fenced code block, formatted by the code formatter for
+// the language in its info string if there is one. Errors are reported to the diagnostic handler,
+// and leave the code as it is.
+func (r *Renderer) formatCode(lines *text.Segments, info []byte) []byte {
+	var code []byte
+	for i := 0; i < lines.Len(); i++ {
+		segment := lines.At(i)
+		code = append(code, segment.Value(r.rc.source)...)
+	}
+	if len(code) > 0 && code[len(code)-1] != lineDelim {
+		code = append(code, lineDelim)
+	}
+	var language string
+	if fields := bytes.Fields(info); len(fields) > 0 {
+		language = string(fields[0])
+	}
+	formatter := r.config.CodeFormatters[language]
+	if formatter == nil || len(code) == 0 {
+		return code
+	}
+	var formatted []byte
+	var err error
+	if _, ok := formatter.(markdownCodeFormatter); ok {
+		formatted, err = r.formatMarkdown(code)
+	} else {
+		formatted, err = formatter.FormatCode(code)
+	}
+	if err != nil {
+		if r.config.DiagnosticHandler != nil {
+			line := lineNumber(r.rc.source, lines.At(0).Start)
+			r.config.DiagnosticHandler(&CodeFormatError{Language: language, Line: line, Err: err})
+		}
+		return code
+	}
+	if len(formatted) > 0 && formatted[len(formatted)-1] != lineDelim {
+		formatted = append(formatted, lineDelim)
+	}
+	return formatted
+}
+
+// formatMarkdown formats markdown code by parsing it with the parser of the goldmark.Markdown that
+// the renderer was added to as an extension, or the default parser, and rendering it.
+func (r *Renderer) formatMarkdown(code []byte) ([]byte, error) {
+	p := r.parser
+	if p == nil {
+		p = goldmark.DefaultParser()
+		p.AddOptions(linkReferenceParserOptions()...)
+	}
+	doc := p.Parse(text.NewReader(code))
+	// Render the code with this renderer, and restore the state of the document being rendered
+	saved, config := r.rc, r.config
+	defer func() { r.rc, r.config = saved, config }()
+	if r.rc.writer != nil {
+		// The code is written after the fence line, inside the prefixes of the code block, so its
+		// prose is wrapped to the width left by them
+		nested := *r.config
+		nested.LineWidth -= LineWidth(r.rc.writer.prefixWidth(1))
+		r.config = &nested
+	}
+	buf := bytes.Buffer{}
+	err := r.Render(&buf, code, doc)
+	return buf.Bytes(), err
+}
@@ -0,0 +1,104 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+)
+
+// TestFormatCode tests that the code of fenced code blocks is formatted by language.
+func TestFormatCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{
+			"Go",
+			"```go\nfunc  main( ) {\nfmt.Println(\"hi\")}\n```",
+			"```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n",
+		},
+		{
+			"JSON",
+			"```json\n{\"a\": [1,2]}\n```",
+			"```json\n{\n  \"a\": [\n    1,\n    2\n  ]\n}\n```\n",
+		},
+		{
+			"Markdown",
+			"````md\nTitle\n=====\n\n```json\n{}\n```\n````",
+			"````md\n# Title\n\n```json\n{}\n```\n````\n",
+		},
+		{
+			"Markdown in list",
+			"- ```markdown\n  * foo\n  ```",
+			"- ```markdown\n  - foo\n  ```\n",
+		},
+		{
+			"Unknown language",
+			"```python\ndef  foo(): pass\n```",
+			"```python\ndef  foo(): pass\n```\n",
+		},
+		{
+			"No info string",
+			"```\n{\"a\":1}\n```",
+			"```\n{\"a\":1}\n```\n",
+		},
+	}
+	md := goldmark.New(goldmark.WithExtensions(NewExtension(
+		WithCodeFormatters(DefaultCodeFormatters()), WithBulletListMarker(BulletListMarkerDash))))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			err := md.Convert([]byte(tc.source), &buf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+// TestFormatMarkdownLineWidth tests that markdown code is wrapped to the line width left by the
+// prefixes of the code block.
+func TestFormatMarkdownLineWidth(t *testing.T) {
+	md := goldmark.New(goldmark.WithExtensions(NewExtension(
+		WithCodeFormatters(DefaultCodeFormatters()), WithProseWrap(ProseWrapAlways), WithLineWidth(20))))
+	source := "```md\nfoo bar baz qux quux corge\n```\n\n> - ```md\n>   foo bar baz qux quux corge\n>   ```"
+	buf := bytes.Buffer{}
+	err := md.Convert([]byte(source), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "```md\nfoo bar baz qux quux\ncorge\n```\n\n> - ```md\n>   foo bar baz qux\n>   quux corge\n>   ```\n", buf.String())
+}
+
+// TestFormatCodeError tests that code that can't be formatted is rendered as it is, and that the
+// error is reported to the diagnostic handler.
+func TestFormatCodeError(t *testing.T) {
+	var diagnostics []error
+	md := goldmark.New(goldmark.WithRenderer(NewRenderer(
+		WithCodeFormatters(DefaultCodeFormatters()),
+		WithDiagnosticHandler(func(err error) { diagnostics = append(diagnostics, err) }),
+	)))
+	source := "foo\n\n```json\n{\"a\":\n```\n\n```go\nfunc(\n```\n"
+	buf := bytes.Buffer{}
+	err := md.Convert([]byte(source), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, source, buf.String())
+
+	if assert.Len(t, diagnostics, 2) {
+		var formatErr *CodeFormatError
+		assert.True(t, errors.As(diagnostics[0], &formatErr))
+		assert.Equal(t, "json", formatErr.Language)
+		assert.Equal(t, 4, formatErr.Line)
+		assert.True(t, errors.As(diagnostics[1], &formatErr))
+		assert.Equal(t, "go", formatErr.Language)
+		assert.Equal(t, 8, formatErr.Line)
+	}
+}
+
+// TestMarkdownCodeFormatter tests formatting markdown outside of a renderer.
+func TestMarkdownCodeFormatter(t *testing.T) {
+	formatted, err := MarkdownCodeFormatter.FormatCode([]byte("Title\n---\n*  foo"))
+	assert.NoError(t, err)
+	assert.Equal(t, "## Title\n* foo\n", string(formatted))
+}
@@ -0,0 +1,182 @@
+package markdown
+
+import (
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// KindLinkReferenceDefinition is a NodeKind of the LinkReferenceDefinition node.
+var KindLinkReferenceDefinition = ast.NewNodeKind("LinkReferenceDefinition")
+
+// A LinkReferenceDefinition represents a link reference definition such as `[label]: /url "title"`.
+// Goldmark removes link reference definitions from the AST while parsing. The parser options added
+// by NewExtension keep them in the AST as LinkReferenceDefinition nodes so they can be rendered.
+type LinkReferenceDefinition struct {
+	ast.BaseBlock
+	// Label is the label of the definition, as written in the source.
+	Label []byte
+	// Destination is the destination of the definition.
+	Destination []byte
+	// Title is the title of the definition, if any.
+	Title []byte
+}
+
+// NewLinkReferenceDefinition returns a new LinkReferenceDefinition node for the given reference.
+func NewLinkReferenceDefinition(ref parser.Reference) *LinkReferenceDefinition {
+	return &LinkReferenceDefinition{
+		Label:       ref.Label(),
+		Destination: ref.Destination(),
+		Title:       ref.Title(),
+	}
+}
+
+// Kind implements ast.Node.Kind.
+func (n *LinkReferenceDefinition) Kind() ast.NodeKind {
+	return KindLinkReferenceDefinition
+}
+
+// Dump implements ast.Node.Dump.
+func (n *LinkReferenceDefinition) Dump(source []byte, level int) {
+	m := map[string]string{
+		"Label":       string(n.Label),
+		"Destination": string(n.Destination),
+		"Title":       string(n.Title),
+	}
+	ast.DumpHelper(n, source, level, m, nil)
+}
+
+// linkReferenceAttribute is the name of the attribute that holds the linkReference of a link or
+// image that referred to a link reference definition in the source.
+const linkReferenceAttribute = "markdown-link-reference"
+
+// linkReferenceForm is the form of a reference link or image.
+type linkReferenceForm int
+
+const (
+	// linkReferenceFull is a full reference link, e.g. `[text][label]`.
+	linkReferenceFull linkReferenceForm = iota + 1
+	// linkReferenceCollapsed is a collapsed reference link, e.g. `[label][]`.
+	linkReferenceCollapsed
+	// linkReferenceShortcut is a shortcut reference link, e.g. `[label]`.
+	linkReferenceShortcut
+)
+
+// linkReference describes how a link or image referred to its link reference definition.
+type linkReference struct {
+	form linkReferenceForm
+	// label is the label of a full reference link, as written in the source
+	label []byte
+}
+
+// linkReferenceParser wraps goldmark's link parser to record which links and images were parsed
+// from references, since goldmark resolves them into links with inline destinations.
+type linkReferenceParser struct {
+	parser.InlineParser
+}
+
+// Parse implements parser.InlineParser.Parse
+func (p *linkReferenceParser) Parse(parent ast.Node, block text.Reader, pc parser.Context) ast.Node {
+	line, segment := block.PeekLine()
+	if line[0] != ']' {
+		return p.InlineParser.Parse(parent, block, pc)
+	}
+	closeLine, closePosition := block.Position()
+	node := p.InlineParser.Parse(parent, block, pc)
+	if node == nil {
+		// Goldmark's own link parser shares its state with this one and runs next if this parser
+		// returns nil, so consume the bracket as text instead.
+		block.SetPosition(closeLine, closePosition)
+		block.Advance(1)
+		return ast.NewTextSegment(segment.WithStop(segment.Start + 1))
+	}
+
+	var ref *linkReference
+	afterLine, afterPosition := block.Position()
+	switch {
+	case afterPosition.Start == segment.Start+1:
+		// Nothing after the closing bracket was consumed
+		ref = &linkReference{form: linkReferenceShortcut}
+	case block.Source()[segment.Start+1] == '[':
+		block.SetPosition(closeLine, closePosition)
+		block.Advance(2)
+		segments, _ := block.FindClosure('[', ']', text.FindClosureOptions{Newline: true, Advance: true})
+		var label []byte
+		for i := 0; i < segments.Len(); i++ {
+			label = append(label, block.Value(segments.At(i))...)
+		}
+		block.SetPosition(afterLine, afterPosition)
+		if util.IsBlank(label) {
+			ref = &linkReference{form: linkReferenceCollapsed}
+		} else {
+			ref = &linkReference{form: linkReferenceFull, label: label}
+		}
+	}
+	if ref != nil {
+		node.SetAttributeString(linkReferenceAttribute, ref)
+	}
+	return node
+}
+
+// linkReferenceDefinitionTransformer is a paragraph transformer that replaces link reference
+// definitions at the start of paragraphs with LinkReferenceDefinition nodes. It runs before
+// goldmark's LinkReferenceParagraphTransformer, which it uses to parse the definitions.
+type linkReferenceDefinitionTransformer struct{}
+
+// Transform implements parser.ParagraphTransformer.Transform
+func (t *linkReferenceDefinitionTransformer) Transform(node *ast.Paragraph, reader text.Reader, pc parser.Context) {
+	parent := node.Parent()
+	prev := node.PreviousSibling()
+	blank := node.HasBlankPreviousLines()
+	recorder := &referenceRecorder{Context: pc}
+	parser.LinkReferenceParagraphTransformer.Transform(node, reader, recorder)
+	if len(recorder.references) == 0 {
+		return
+	}
+
+	next := ast.Node(node)
+	if node.Parent() == nil {
+		// The paragraph only contained definitions, so goldmark replaced it with an empty text block
+		textBlock := parent.FirstChild()
+		if prev != nil {
+			textBlock = prev.NextSibling()
+		}
+		next = textBlock.NextSibling()
+		parent.RemoveChild(parent, textBlock)
+	} else {
+		node.SetBlankPreviousLines(false)
+	}
+	for i, ref := range recorder.references {
+		definition := NewLinkReferenceDefinition(ref)
+		definition.SetBlankPreviousLines(blank && i == 0)
+		if next != nil {
+			parent.InsertBefore(parent, next, definition)
+		} else {
+			parent.AppendChild(parent, definition)
+		}
+	}
+}
+
+// referenceRecorder is a parser.Context that records the link references added to it.
+type referenceRecorder struct {
+	parser.Context
+	references []parser.Reference
+}
+
+// AddReference implements parser.Context.AddReference
+func (c *referenceRecorder) AddReference(ref parser.Reference) {
+	c.references = append(c.references, ref)
+	c.Context.AddReference(ref)
+}
+
+// linkReferenceParserOptions returns the parser options that keep reference links and link
+// reference definitions in the AST.
+func linkReferenceParserOptions() []parser.Option {
+	return []parser.Option{
+		// Goldmark's link parser has a priority of 200
+		parser.WithInlineParsers(util.Prioritized(&linkReferenceParser{parser.NewLinkParser()}, 199)),
+		// Goldmark's LinkReferenceParagraphTransformer has a priority of 100
+		parser.WithParagraphTransformers(util.Prioritized(&linkReferenceDefinitionTransformer{}, 99)),
+	}
+}
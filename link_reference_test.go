@@ -0,0 +1,128 @@
+package markdown
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+// TestLinkReferenceParser tests that links parsed from references are annotated with their form.
+func TestLinkReferenceParser(t *testing.T) {
+	definitions := "\n\n[foo]: /url\n[b c]: /url"
+	testCases := []struct {
+		name     string
+		source   string
+		expected *linkReference
+	}{
+		{"Inline link", "[foo](/url)", nil},
+		{"Full reference", "[text][foo]", &linkReference{form: linkReferenceFull, label: []byte("foo")}},
+		{"Multiline label", "[text][b\nc]", &linkReference{form: linkReferenceFull, label: []byte("b\nc")}},
+		{"Collapsed reference", "[foo][]", &linkReference{form: linkReferenceCollapsed}},
+		{"Shortcut reference", "[foo]", &linkReference{form: linkReferenceShortcut}},
+		{"Shortcut reference before parenthesis", "[foo](not a link", &linkReference{form: linkReferenceShortcut}},
+		{"Image", "![foo][]", &linkReference{form: linkReferenceCollapsed}},
+		{"Brackets in text", "[a [b] c][foo]", &linkReference{form: linkReferenceFull, label: []byte("foo")}},
+	}
+	md := goldmark.New(goldmark.WithExtensions(NewExtension()))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			source := []byte(tc.source + definitions)
+			doc := md.Parser().Parse(text.NewReader(source))
+			var link ast.Node
+			_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+				if entering && link == nil && (n.Kind() == ast.KindLink || n.Kind() == ast.KindImage) {
+					link = n
+				}
+				return ast.WalkContinue, nil
+			})
+			if assert.NotNil(t, link) {
+				assert.Equal(t, tc.expected, linkReferenceOf(link))
+			}
+		})
+	}
+}
+
+// TestLinkReferenceDefinitionTransformer tests that link reference definitions are kept in the AST
+// where they were defined.
+func TestLinkReferenceDefinitionTransformer(t *testing.T) {
+	source := []byte("foo\n\n[a]: /a\n[b]: /b \"title\"\nbar\n\n[c]: /c")
+	md := goldmark.New(goldmark.WithExtensions(NewExtension()))
+	doc := md.Parser().Parse(text.NewReader(source))
+
+	kinds := []ast.NodeKind{}
+	blanks := []bool{}
+	for c := doc.FirstChild(); c != nil; c = c.NextSibling() {
+		kinds = append(kinds, c.Kind())
+		blanks = append(blanks, c.HasBlankPreviousLines())
+	}
+	assert.Equal(t, []ast.NodeKind{
+		ast.KindParagraph,
+		KindLinkReferenceDefinition,
+		KindLinkReferenceDefinition,
+		ast.KindParagraph,
+		KindLinkReferenceDefinition,
+	}, kinds)
+	assert.Equal(t, []bool{true, true, false, false, true}, blanks)
+
+	definition := doc.FirstChild().NextSibling().NextSibling().(*LinkReferenceDefinition)
+	assert.Equal(t, "b", string(definition.Label))
+	assert.Equal(t, "/b", string(definition.Destination))
+	assert.Equal(t, "title", string(definition.Title))
+}
+
+// TestChangedReferenceLink tests that reference links that no longer match their definition are
+// rendered inline.
+func TestChangedReferenceLink(t *testing.T) {
+	source := []byte("[foo] [bar][foo]\n\n[foo]: /url")
+	md := goldmark.New(goldmark.WithExtensions(NewExtension()))
+	doc := md.Parser().Parse(text.NewReader(source))
+	link := doc.FirstChild().FirstChild().(*ast.Link)
+	link.Destination = []byte("/changed")
+
+	buf := bytes.Buffer{}
+	err := md.Renderer().Render(&buf, source, doc)
+	assert.NoError(t, err)
+	assert.Equal(t, "[foo](/changed) [bar][foo]\n\n[foo]: /url\n", buf.String())
+}
+
+// TestMovedLinkReferenceDefinitions tests that moving link reference definitions out of container
+// blocks keeps the containers, and renders markdown with the same HTML as the source.
+func TestMovedLinkReferenceDefinitions(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Only block in blockquote", "[foo]\n\n> [foo]: /url\n", "[foo]\n\n>\n\n[foo]: /url\n"},
+		{"Only block in list item", "- [foo]: /url\n- [foo]\n", "-\n- [foo]\n\n[foo]: /url\n"},
+		{"Between loose list items", "- a\n- b\n\n  [ref]: /url\n- d\n", "- a\n\n- b\n\n- d\n\n[ref]: /url\n"},
+	}
+	placements := []struct {
+		name      string
+		placement LinkReferenceDefinitionPlacement
+	}{
+		{"DocumentEnd", LinkReferenceDefinitionPlacementDocumentEnd},
+		{"Sorted", LinkReferenceDefinitionPlacementSorted},
+	}
+	for _, tc := range testCases {
+		for _, p := range placements {
+			t.Run(tc.name+"/"+p.name, func(t *testing.T) {
+				md := goldmark.New(goldmark.WithExtensions(NewExtension(
+					WithLinkReferenceDefinitionPlacement(p.placement), WithMaxBlankLines(1))))
+				buf := bytes.Buffer{}
+				err := md.Convert([]byte(tc.source), &buf)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, buf.String())
+
+				expectedHTML, html := bytes.Buffer{}, bytes.Buffer{}
+				assert.NoError(t, goldmark.Convert([]byte(tc.source), &expectedHTML))
+				assert.NoError(t, goldmark.Convert(buf.Bytes(), &html))
+				assert.Equal(t, expectedHTML.String(), html.String())
+			})
+		}
+	}
+}
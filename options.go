@@ -12,6 +12,33 @@ type Config struct {
 	ThematicBreakLength
 	NestedListLength
 	TypographerSubstitutions
+	StrikethroughStyle
+	TaskCheckBoxStyle
+	FootnoteLabelStyle
+	FootnotePlacement
+	LinkReferenceDefinitionPlacement
+	LinkStyle
+	LinkLabelStyle
+	LineWidth
+	ProseWrap
+	EmphasisStyle
+	StrongStyle
+	BulletListMarker
+	OrderedListNumbering
+	OrderedListDelimiter
+	CodeFenceStyle
+	CodeBlockStyle
+	CodeBlockInfo
+	CodeFormatters
+	DiagnosticHandler
+	HardLineBreakStyle
+	LineEnding
+	FinalNewline
+	ByteOrderMark
+	MaxBlankLines
+	BlockSpacing
+	ListSpacing
+	UnsupportedNodeHandling
 }
 
 // NewConfig returns a new Config with defaults and the given options.
@@ -22,6 +49,8 @@ func NewConfig(options ...Option) *Config {
 		ThematicBreakStyle:  ThematicBreakStyle(ThematicBreakStyleDashed),
 		ThematicBreakLength: ThematicBreakLength(ThematicBreakLengthMinimum),
 		NestedListLength:    NestedListLength(NestedListLengthMinimum),
+		LineWidth:           LineWidth(LineWidthDefault),
+		MaxBlankLines:       MaxBlankLines(MaxBlankLinesMinimum),
 	}
 	for _, opt := range options {
 		opt.SetMarkdownOption(c)
@@ -42,6 +71,60 @@ func (c *Config) SetOption(name renderer.OptionName, value interface{}) {
 		c.ThematicBreakLength = value.(ThematicBreakLength)
 	case optNestedListLength:
 		c.NestedListLength = value.(NestedListLength)
+	case optStrikethroughStyle:
+		c.StrikethroughStyle = value.(StrikethroughStyle)
+	case optTaskCheckBoxStyle:
+		c.TaskCheckBoxStyle = value.(TaskCheckBoxStyle)
+	case optFootnoteLabelStyle:
+		c.FootnoteLabelStyle = value.(FootnoteLabelStyle)
+	case optFootnotePlacement:
+		c.FootnotePlacement = value.(FootnotePlacement)
+	case optLinkReferenceDefinitionPlacement:
+		c.LinkReferenceDefinitionPlacement = value.(LinkReferenceDefinitionPlacement)
+	case optLinkStyle:
+		c.LinkStyle = value.(LinkStyle)
+	case optLinkLabelStyle:
+		c.LinkLabelStyle = value.(LinkLabelStyle)
+	case optLineWidth:
+		c.LineWidth = value.(LineWidth)
+	case optProseWrap:
+		c.ProseWrap = value.(ProseWrap)
+	case optEmphasisStyle:
+		c.EmphasisStyle = value.(EmphasisStyle)
+	case optStrongStyle:
+		c.StrongStyle = value.(StrongStyle)
+	case optBulletListMarker:
+		c.BulletListMarker = value.(BulletListMarker)
+	case optOrderedListNumbering:
+		c.OrderedListNumbering = value.(OrderedListNumbering)
+	case optOrderedListDelimiter:
+		c.OrderedListDelimiter = value.(OrderedListDelimiter)
+	case optCodeFenceStyle:
+		c.CodeFenceStyle = value.(CodeFenceStyle)
+	case optCodeBlockStyle:
+		c.CodeBlockStyle = value.(CodeBlockStyle)
+	case optCodeBlockInfo:
+		c.CodeBlockInfo = value.(CodeBlockInfo)
+	case optCodeFormatters:
+		c.CodeFormatters = value.(CodeFormatters)
+	case optDiagnosticHandler:
+		c.DiagnosticHandler = value.(DiagnosticHandler)
+	case optHardLineBreakStyle:
+		c.HardLineBreakStyle = value.(HardLineBreakStyle)
+	case optLineEnding:
+		c.LineEnding = value.(LineEnding)
+	case optFinalNewline:
+		c.FinalNewline = value.(FinalNewline)
+	case optByteOrderMark:
+		c.ByteOrderMark = value.(ByteOrderMark)
+	case optMaxBlankLines:
+		c.MaxBlankLines = value.(MaxBlankLines)
+	case optBlockSpacing:
+		c.BlockSpacing = value.(BlockSpacing)
+	case optListSpacing:
+		c.ListSpacing = value.(ListSpacing)
+	case optUnsupportedNodeHandling:
+		c.UnsupportedNodeHandling = value.(UnsupportedNodeHandling)
 	}
 }
 
@@ -59,13 +142,17 @@ type Option interface {
 // optIndentStyle is an option name used in WithIndentStyle
 const optIndentStyle renderer.OptionName = "IndentStyle"
 
-// IndentStyle is an enum expressing how markdown blocks should be indented.
+// IndentStyle is an enum expressing how markdown blocks should be indented. It applies to the
+// continuation lines of list items, definition descriptions and footnotes, and to indented code.
 type IndentStyle int
 
 const (
-	// IndentStyleSpaces indents with 4 spaces. This is the default as well as the zero-value.
+	// IndentStyleSpaces indents with spaces. This is the default as well as the zero-value.
 	IndentStyleSpaces = iota
-	// IndentStyleTabs indents with tabs.
+	// IndentStyleTabs indents with a tab for each tab stop the indentation reaches, and spaces for
+	// the rest. Tab stops are every 4 columns, so list items indented by less than 4 columns are
+	// indented with spaces.
+	// Ex: "- foo\n\n      code" with tabs is "- foo\n\n  \t  code"
 	IndentStyleTabs
 )
 
@@ -302,3 +389,1197 @@ func WithTypographerSubstitutions(enabled TypographerSubstitutions) interface {
 } {
 	return &withTypographerSubstitutions{enabled}
 }
+
+// ============================================================================
+// StrikethroughStyle Option
+// ============================================================================
+
+// optStrikethroughStyle is an option name used in WithStrikethroughStyle
+const optStrikethroughStyle renderer.OptionName = "StrikethroughStyle"
+
+// StrikethroughStyle is an enum expressing the delimiters used for strikethrough text.
+type StrikethroughStyle int
+
+const (
+	// StrikethroughStyleDoubleTilde surrounds strikethrough text with two tildes. This is the
+	// default and zero value.
+	// Ex: ~~foo~~
+	StrikethroughStyleDoubleTilde = iota
+	// StrikethroughStyleSingleTilde surrounds strikethrough text with a single tilde.
+	// Ex: ~foo~
+	StrikethroughStyleSingleTilde
+)
+
+// Delimiter returns the delimiter used for the strikethrough style
+func (s StrikethroughStyle) Delimiter() []byte {
+	return [...][]byte{[]byte("~~"), []byte("~")}[s]
+}
+
+type withStrikethroughStyle struct {
+	value StrikethroughStyle
+}
+
+func (o *withStrikethroughStyle) SetConfig(c *renderer.Config) {
+	c.Options[optStrikethroughStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withStrikethroughStyle) SetMarkdownOption(c *Config) {
+	c.StrikethroughStyle = o.value
+}
+
+// WithStrikethroughStyle is a functional option that sets the delimiters used for strikethrough
+// text. This setting has no effect unless the strikethrough extension is enabled.
+func WithStrikethroughStyle(style StrikethroughStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withStrikethroughStyle{style}
+}
+
+// ============================================================================
+// TaskCheckBoxStyle Option
+// ============================================================================
+
+// optTaskCheckBoxStyle is an option name used in WithTaskCheckBoxStyle
+const optTaskCheckBoxStyle renderer.OptionName = "TaskCheckBoxStyle"
+
+// TaskCheckBoxStyle is an enum expressing how checked task list items should look.
+type TaskCheckBoxStyle int
+
+const (
+	// TaskCheckBoxStyleLowercase marks checked tasks with a lowercase x. This is the default and
+	// zero value.
+	// Ex: - [x] foo
+	TaskCheckBoxStyleLowercase = iota
+	// TaskCheckBoxStyleUppercase marks checked tasks with an uppercase X.
+	// Ex: - [X] foo
+	TaskCheckBoxStyleUppercase
+)
+
+// CheckBox returns the check box for a task with the given checked state
+func (t TaskCheckBoxStyle) CheckBox(checked bool) []byte {
+	if !checked {
+		return []byte("[ ]")
+	}
+	return [...][]byte{[]byte("[x]"), []byte("[X]")}[t]
+}
+
+type withTaskCheckBoxStyle struct {
+	value TaskCheckBoxStyle
+}
+
+func (o *withTaskCheckBoxStyle) SetConfig(c *renderer.Config) {
+	c.Options[optTaskCheckBoxStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withTaskCheckBoxStyle) SetMarkdownOption(c *Config) {
+	c.TaskCheckBoxStyle = o.value
+}
+
+// WithTaskCheckBoxStyle is a functional option that sets the casing of checked task list items.
+// This setting has no effect unless the task list extension is enabled.
+func WithTaskCheckBoxStyle(style TaskCheckBoxStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withTaskCheckBoxStyle{style}
+}
+
+// ============================================================================
+// FootnoteLabelStyle Option
+// ============================================================================
+
+// optFootnoteLabelStyle is an option name used in WithFootnoteLabelStyle
+const optFootnoteLabelStyle renderer.OptionName = "FootnoteLabelStyle"
+
+// FootnoteLabelStyle is an enum expressing how footnote labels should be rendered.
+type FootnoteLabelStyle int
+
+const (
+	// FootnoteLabelStyleOriginal keeps the footnote labels used in the source. This is the default
+	// and zero value.
+	// Ex: [^note]
+	FootnoteLabelStyleOriginal = iota
+	// FootnoteLabelStyleNumbered renumbers footnotes sequentially in order of first reference.
+	// Ex: [^1]
+	FootnoteLabelStyleNumbered
+)
+
+type withFootnoteLabelStyle struct {
+	value FootnoteLabelStyle
+}
+
+func (o *withFootnoteLabelStyle) SetConfig(c *renderer.Config) {
+	c.Options[optFootnoteLabelStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withFootnoteLabelStyle) SetMarkdownOption(c *Config) {
+	c.FootnoteLabelStyle = o.value
+}
+
+// WithFootnoteLabelStyle is a functional option that sets how footnote labels are rendered.
+// This setting has no effect unless the footnote extension is enabled.
+func WithFootnoteLabelStyle(style FootnoteLabelStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withFootnoteLabelStyle{style}
+}
+
+// ============================================================================
+// FootnotePlacement Option
+// ============================================================================
+
+// optFootnotePlacement is an option name used in WithFootnotePlacement
+const optFootnotePlacement renderer.OptionName = "FootnotePlacement"
+
+// FootnotePlacement is an enum expressing where footnote definitions should be rendered.
+type FootnotePlacement int
+
+const (
+	// FootnotePlacementDocumentEnd renders all footnote definitions at the end of the document.
+	// This is the default and zero value.
+	FootnotePlacementDocumentEnd = iota
+	// FootnotePlacementSectionEnd renders footnote definitions at the end of the section where
+	// they are first referenced. Sections are delimited by headings at the top level of the
+	// document.
+	FootnotePlacementSectionEnd
+)
+
+type withFootnotePlacement struct {
+	value FootnotePlacement
+}
+
+func (o *withFootnotePlacement) SetConfig(c *renderer.Config) {
+	c.Options[optFootnotePlacement] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withFootnotePlacement) SetMarkdownOption(c *Config) {
+	c.FootnotePlacement = o.value
+}
+
+// WithFootnotePlacement is a functional option that sets where footnote definitions are rendered.
+// This setting has no effect unless the footnote extension is enabled.
+func WithFootnotePlacement(placement FootnotePlacement) interface {
+	renderer.Option
+	Option
+} {
+	return &withFootnotePlacement{placement}
+}
+
+// ============================================================================
+// LinkReferenceDefinitionPlacement Option
+// ============================================================================
+
+// optLinkReferenceDefinitionPlacement is an option name used in WithLinkReferenceDefinitionPlacement
+const optLinkReferenceDefinitionPlacement renderer.OptionName = "LinkReferenceDefinitionPlacement"
+
+// LinkReferenceDefinitionPlacement is an enum expressing where link reference definitions should be
+// rendered.
+type LinkReferenceDefinitionPlacement int
+
+const (
+	// LinkReferenceDefinitionPlacementPreserve renders link reference definitions where they were
+	// defined in the source. This is the default and zero value.
+	LinkReferenceDefinitionPlacementPreserve = iota
+	// LinkReferenceDefinitionPlacementDocumentEnd renders link reference definitions at the end of
+	// the document, in the order they were defined. Duplicate definitions, which have no effect, are
+	// removed.
+	LinkReferenceDefinitionPlacementDocumentEnd
+	// LinkReferenceDefinitionPlacementSorted renders link reference definitions at the end of the
+	// document, sorted by label. Duplicate definitions are removed.
+	LinkReferenceDefinitionPlacementSorted
+)
+
+type withLinkReferenceDefinitionPlacement struct {
+	value LinkReferenceDefinitionPlacement
+}
+
+func (o *withLinkReferenceDefinitionPlacement) SetConfig(c *renderer.Config) {
+	c.Options[optLinkReferenceDefinitionPlacement] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withLinkReferenceDefinitionPlacement) SetMarkdownOption(c *Config) {
+	c.LinkReferenceDefinitionPlacement = o.value
+}
+
+// WithLinkReferenceDefinitionPlacement is a functional option that sets where link reference
+// definitions are rendered. Link reference definitions are only kept in the AST if the renderer is
+// added as an extension (e.g. via NewExtension).
+func WithLinkReferenceDefinitionPlacement(placement LinkReferenceDefinitionPlacement) interface {
+	renderer.Option
+	Option
+} {
+	return &withLinkReferenceDefinitionPlacement{placement}
+}
+
+// ============================================================================
+// LinkStyle Option
+// ============================================================================
+
+// optLinkStyle is an option name used in WithLinkStyle
+const optLinkStyle renderer.OptionName = "LinkStyle"
+
+// LinkStyle is an enum expressing how links and images should be rendered.
+type LinkStyle int
+
+const (
+	// LinkStylePreserve renders inline links inline, and reference links as references. This is the
+	// default and zero value.
+	LinkStylePreserve = iota
+	// LinkStyleInline renders all links inline.
+	// Ex: [text](https://example.com)
+	LinkStyleInline
+	// LinkStyleReferenceDocumentEnd renders inline links as references, with the generated link
+	// reference definitions at the end of the document.
+	// Ex: [text]
+	//     [text]: https://example.com
+	LinkStyleReferenceDocumentEnd
+	// LinkStyleReferenceSectionEnd renders inline links as references, with the generated link
+	// reference definitions at the end of the section where they are first used. Sections are
+	// delimited by headings at the top level of the document.
+	LinkStyleReferenceSectionEnd
+)
+
+// IsReference returns true if link style is one of the reference options
+func (s LinkStyle) IsReference() bool {
+	return s == LinkStyleReferenceDocumentEnd || s == LinkStyleReferenceSectionEnd
+}
+
+type withLinkStyle struct {
+	value LinkStyle
+}
+
+func (o *withLinkStyle) SetConfig(c *renderer.Config) {
+	c.Options[optLinkStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withLinkStyle) SetMarkdownOption(c *Config) {
+	c.LinkStyle = o.value
+}
+
+// WithLinkStyle is a functional option that sets how links and images are rendered. Links that
+// refer to the same destination and title share a link reference definition.
+func WithLinkStyle(style LinkStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withLinkStyle{style}
+}
+
+// ============================================================================
+// LinkLabelStyle Option
+// ============================================================================
+
+// optLinkLabelStyle is an option name used in WithLinkLabelStyle
+const optLinkLabelStyle renderer.OptionName = "LinkLabelStyle"
+
+// LinkLabelStyle is an enum expressing how labels are generated for links rendered as references.
+type LinkLabelStyle int
+
+const (
+	// LinkLabelStyleText uses the link text as the label where possible, and falls back to a number
+	// if the text can't be used as a label or its label is already taken. This is the default and
+	// zero value.
+	// Ex: [text]
+	LinkLabelStyleText = iota
+	// LinkLabelStyleNumbered numbers labels sequentially in order of first use.
+	// Ex: [text][1]
+	LinkLabelStyleNumbered
+)
+
+type withLinkLabelStyle struct {
+	value LinkLabelStyle
+}
+
+func (o *withLinkLabelStyle) SetConfig(c *renderer.Config) {
+	c.Options[optLinkLabelStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withLinkLabelStyle) SetMarkdownOption(c *Config) {
+	c.LinkLabelStyle = o.value
+}
+
+// WithLinkLabelStyle is a functional option that sets how labels are generated for links rendered
+// as references. This setting has no effect unless links are rendered as references.
+func WithLinkLabelStyle(style LinkLabelStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withLinkLabelStyle{style}
+}
+
+// ============================================================================
+// LineWidth Option
+// ============================================================================
+
+// optLineWidth is an option name used in WithLineWidth
+const optLineWidth renderer.OptionName = "LineWidth"
+
+// LineWidth configures the maximum width of lines, in columns, that prose is wrapped to
+type LineWidth int
+
+const (
+	// LineWidthDefault is the default line width.
+	LineWidthDefault = 80
+)
+
+type withLineWidth struct {
+	value LineWidth
+}
+
+func (o *withLineWidth) SetConfig(c *renderer.Config) {
+	c.Options[optLineWidth] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withLineWidth) SetMarkdownOption(c *Config) {
+	c.LineWidth = o.value
+}
+
+// WithLineWidth is a functional option that sets the maximum width of lines that prose is wrapped
+// to. The width includes the indentation and markers of the blocks containing the prose. This
+// setting has no effect unless prose wrap is set to ProseWrapAlways.
+func WithLineWidth(width LineWidth) interface {
+	renderer.Option
+	Option
+} {
+	return &withLineWidth{width}
+}
+
+// ============================================================================
+// ProseWrap Option
+// ============================================================================
+
+// optProseWrap is an option name used in WithProseWrap
+const optProseWrap renderer.OptionName = "ProseWrap"
+
+// ProseWrap is an enum expressing how the text of paragraphs should be wrapped.
+type ProseWrap int
+
+const (
+	// ProseWrapPreserve keeps line breaks in paragraphs as they are in the source. This is the
+	// default and zero value.
+	ProseWrapPreserve = iota
+	// ProseWrapAlways reflows paragraphs so their lines fit within the line width where possible.
+	// Lines are never broken inside code spans, link destinations, autolinks, or raw HTML, or
+	// where the next line would start a new block.
+	ProseWrapAlways
+	// ProseWrapNever joins the lines of paragraphs, so that each paragraph is written on a single
+	// line except for hard line breaks.
+	ProseWrapNever
+	// ProseWrapSentence starts each sentence on a new line, and joins the other lines of paragraphs.
+	// Periods in common abbreviations, initialisms, and code spans are not treated as the end of a
+	// sentence.
+	// Ex: The first sentence.
+	//     The second sentence.
+	ProseWrapSentence
+)
+
+type withProseWrap struct {
+	value ProseWrap
+}
+
+func (o *withProseWrap) SetConfig(c *renderer.Config) {
+	c.Options[optProseWrap] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withProseWrap) SetMarkdownOption(c *Config) {
+	c.ProseWrap = o.value
+}
+
+// WithProseWrap is a functional option that sets how the text of paragraphs is wrapped.
+func WithProseWrap(wrap ProseWrap) interface {
+	renderer.Option
+	Option
+} {
+	return &withProseWrap{wrap}
+}
+
+// ============================================================================
+// EmphasisStyle Option
+// ============================================================================
+
+// optEmphasisStyle is an option name used in WithEmphasisStyle
+const optEmphasisStyle renderer.OptionName = "EmphasisStyle"
+
+// EmphasisStyle is an enum expressing the delimiter used for emphasis.
+type EmphasisStyle int
+
+const (
+	// EmphasisStyleAsterisk surrounds emphasized text with asterisks. This is the default and zero
+	// value.
+	// Ex: *foo*
+	EmphasisStyleAsterisk = iota
+	// EmphasisStyleUnderscore surrounds emphasized text with underscores. Asterisks are used
+	// instead where underscores can't delimit emphasis, such as inside words.
+	// Ex: _foo_
+	EmphasisStyleUnderscore
+	// EmphasisStylePreserve keeps the delimiter that emphasis was written with in the source.
+	// Emphasis added by transformers is rendered with asterisks.
+	EmphasisStylePreserve
+)
+
+type withEmphasisStyle struct {
+	value EmphasisStyle
+}
+
+func (o *withEmphasisStyle) SetConfig(c *renderer.Config) {
+	c.Options[optEmphasisStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withEmphasisStyle) SetMarkdownOption(c *Config) {
+	c.EmphasisStyle = o.value
+}
+
+// WithEmphasisStyle is a functional option that sets the delimiter used for emphasis.
+func WithEmphasisStyle(style EmphasisStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withEmphasisStyle{style}
+}
+
+// ============================================================================
+// StrongStyle Option
+// ============================================================================
+
+// optStrongStyle is an option name used in WithStrongStyle
+const optStrongStyle renderer.OptionName = "StrongStyle"
+
+// StrongStyle is an enum expressing the delimiters used for strong emphasis.
+type StrongStyle int
+
+const (
+	// StrongStyleAsterisk surrounds strongly emphasized text with two asterisks. This is the
+	// default and zero value.
+	// Ex: **foo**
+	StrongStyleAsterisk = iota
+	// StrongStyleUnderscore surrounds strongly emphasized text with two underscores. Asterisks are
+	// used instead where underscores can't delimit emphasis, such as inside words.
+	// Ex: __foo__
+	StrongStyleUnderscore
+	// StrongStylePreserve keeps the delimiters that strong emphasis was written with in the source.
+	// Strong emphasis added by transformers is rendered with asterisks.
+	StrongStylePreserve
+)
+
+type withStrongStyle struct {
+	value StrongStyle
+}
+
+func (o *withStrongStyle) SetConfig(c *renderer.Config) {
+	c.Options[optStrongStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withStrongStyle) SetMarkdownOption(c *Config) {
+	c.StrongStyle = o.value
+}
+
+// WithStrongStyle is a functional option that sets the delimiters used for strong emphasis.
+func WithStrongStyle(style StrongStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withStrongStyle{style}
+}
+
+// ============================================================================
+// BulletListMarker Option
+// ============================================================================
+
+// optBulletListMarker is an option name used in WithBulletListMarker
+const optBulletListMarker renderer.OptionName = "BulletListMarker"
+
+// BulletListMarker is an enum expressing the markers used for the items of bullet lists.
+type BulletListMarker int
+
+const (
+	// BulletListMarkerPreserve keeps the marker that each list was written with in the source.
+	// This is the default and zero value.
+	BulletListMarkerPreserve = iota
+	// BulletListMarkerDash marks list items with a dash.
+	// Ex: - foo
+	BulletListMarkerDash
+	// BulletListMarkerAsterisk marks list items with an asterisk.
+	// Ex: * foo
+	BulletListMarkerAsterisk
+	// BulletListMarkerPlus marks list items with a plus sign.
+	// Ex: + foo
+	BulletListMarkerPlus
+	// BulletListMarkerAlternate marks list items with a dash, asterisk, or plus sign depending on
+	// how deeply the list is nested.
+	// Ex: - foo
+	//       * bar
+	//         + baz
+	BulletListMarkerAlternate
+)
+
+type withBulletListMarker struct {
+	value BulletListMarker
+}
+
+func (o *withBulletListMarker) SetConfig(c *renderer.Config) {
+	c.Options[optBulletListMarker] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withBulletListMarker) SetMarkdownOption(c *Config) {
+	c.BulletListMarker = o.value
+}
+
+// WithBulletListMarker is a functional option that sets the markers used for the items of bullet
+// lists.
+func WithBulletListMarker(marker BulletListMarker) interface {
+	renderer.Option
+	Option
+} {
+	return &withBulletListMarker{marker}
+}
+
+// ============================================================================
+// OrderedListNumbering Option
+// ============================================================================
+
+// optOrderedListNumbering is an option name used in WithOrderedListNumbering
+const optOrderedListNumbering renderer.OptionName = "OrderedListNumbering"
+
+// OrderedListNumbering is an enum expressing how the items of ordered lists are numbered.
+type OrderedListNumbering int
+
+const (
+	// OrderedListNumberingIncrementing numbers list items sequentially from the start number of
+	// the list. This is the default and zero value.
+	// Ex: 3. foo
+	//     4. bar
+	OrderedListNumberingIncrementing = iota
+	// OrderedListNumberingAllOnes numbers every list item with the start number of the list, which
+	// is usually one.
+	// Ex: 1. foo
+	//     1. bar
+	OrderedListNumberingAllOnes
+	// OrderedListNumberingPreserve keeps the numbers that list items were written with in the
+	// source. Items whose number can't be found in the source are numbered after the previous item.
+	OrderedListNumberingPreserve
+	// OrderedListNumberingStartAtOne numbers list items sequentially from one, regardless of the
+	// start number of the list.
+	// Ex: 1. foo
+	//     2. bar
+	OrderedListNumberingStartAtOne
+)
+
+type withOrderedListNumbering struct {
+	value OrderedListNumbering
+}
+
+func (o *withOrderedListNumbering) SetConfig(c *renderer.Config) {
+	c.Options[optOrderedListNumbering] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withOrderedListNumbering) SetMarkdownOption(c *Config) {
+	c.OrderedListNumbering = o.value
+}
+
+// WithOrderedListNumbering is a functional option that sets how the items of ordered lists are
+// numbered.
+func WithOrderedListNumbering(numbering OrderedListNumbering) interface {
+	renderer.Option
+	Option
+} {
+	return &withOrderedListNumbering{numbering}
+}
+
+// ============================================================================
+// OrderedListDelimiter Option
+// ============================================================================
+
+// optOrderedListDelimiter is an option name used in WithOrderedListDelimiter
+const optOrderedListDelimiter renderer.OptionName = "OrderedListDelimiter"
+
+// OrderedListDelimiter is an enum expressing the delimiter that follows the numbers of ordered list
+// items.
+type OrderedListDelimiter int
+
+const (
+	// OrderedListDelimiterPreserve keeps the delimiter that each list was written with in the
+	// source. This is the default and zero value.
+	OrderedListDelimiterPreserve = iota
+	// OrderedListDelimiterPeriod follows the numbers of list items with a period.
+	// Ex: 1. foo
+	OrderedListDelimiterPeriod
+	// OrderedListDelimiterParenthesis follows the numbers of list items with a closing parenthesis.
+	// Ex: 1) foo
+	OrderedListDelimiterParenthesis
+)
+
+type withOrderedListDelimiter struct {
+	value OrderedListDelimiter
+}
+
+func (o *withOrderedListDelimiter) SetConfig(c *renderer.Config) {
+	c.Options[optOrderedListDelimiter] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withOrderedListDelimiter) SetMarkdownOption(c *Config) {
+	c.OrderedListDelimiter = o.value
+}
+
+// WithOrderedListDelimiter is a functional option that sets the delimiter that follows the numbers
+// of ordered list items.
+func WithOrderedListDelimiter(delimiter OrderedListDelimiter) interface {
+	renderer.Option
+	Option
+} {
+	return &withOrderedListDelimiter{delimiter}
+}
+
+// ============================================================================
+// CodeFenceStyle Option
+// ============================================================================
+
+// optCodeFenceStyle is an option name used in WithCodeFenceStyle
+const optCodeFenceStyle renderer.OptionName = "CodeFenceStyle"
+
+// CodeFenceStyle is an enum expressing the character used for the fences of fenced code blocks.
+type CodeFenceStyle int
+
+const (
+	// CodeFenceStyleBacktick fences code blocks with backticks. This is the default and zero value.
+	// Code blocks whose info string contains a backtick are fenced with tildes instead.
+	// Ex: ```go
+	CodeFenceStyleBacktick = iota
+	// CodeFenceStyleTilde fences code blocks with tildes.
+	// Ex: ~~~go
+	CodeFenceStyleTilde
+)
+
+// FenceChar returns the character used for code fences in the code fence style.
+func (s CodeFenceStyle) FenceChar() byte {
+	return [...]byte{'`', '~'}[s]
+}
+
+type withCodeFenceStyle struct {
+	value CodeFenceStyle
+}
+
+func (o *withCodeFenceStyle) SetConfig(c *renderer.Config) {
+	c.Options[optCodeFenceStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withCodeFenceStyle) SetMarkdownOption(c *Config) {
+	c.CodeFenceStyle = o.value
+}
+
+// WithCodeFenceStyle is a functional option that sets the character used for the fences of fenced
+// code blocks.
+func WithCodeFenceStyle(style CodeFenceStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeFenceStyle{style}
+}
+
+// ============================================================================
+// CodeBlockStyle Option
+// ============================================================================
+
+// optCodeBlockStyle is an option name used in WithCodeBlockStyle
+const optCodeBlockStyle renderer.OptionName = "CodeBlockStyle"
+
+// CodeBlockStyle is an enum expressing how code blocks should be rendered.
+type CodeBlockStyle int
+
+const (
+	// CodeBlockStylePreserve renders indented and fenced code blocks as they were written in the
+	// source. This is the default and zero value.
+	CodeBlockStylePreserve = iota
+	// CodeBlockStyleFenced renders indented code blocks as fenced code blocks, with the info string
+	// set by WithCodeBlockInfo.
+	// Ex: ```
+	//     foo
+	//     ```
+	CodeBlockStyleFenced
+	// CodeBlockStyleIndented renders fenced code blocks without an info string as indented code
+	// blocks, using the indent style. Code blocks that can't be indented, such as those that start
+	// or end with a blank line or directly follow another indented code block, stay fenced.
+	// Ex:     foo
+	CodeBlockStyleIndented
+)
+
+type withCodeBlockStyle struct {
+	value CodeBlockStyle
+}
+
+func (o *withCodeBlockStyle) SetConfig(c *renderer.Config) {
+	c.Options[optCodeBlockStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withCodeBlockStyle) SetMarkdownOption(c *Config) {
+	c.CodeBlockStyle = o.value
+}
+
+// WithCodeBlockStyle is a functional option that sets how code blocks are rendered.
+func WithCodeBlockStyle(style CodeBlockStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeBlockStyle{style}
+}
+
+// ============================================================================
+// CodeBlockInfo Option
+// ============================================================================
+
+// optCodeBlockInfo is an option name used in WithCodeBlockInfo
+const optCodeBlockInfo renderer.OptionName = "CodeBlockInfo"
+
+// CodeBlockInfo configures the info string of fenced code blocks rendered from indented code
+// blocks, such as the name of the language of the code.
+type CodeBlockInfo string
+
+type withCodeBlockInfo struct {
+	value CodeBlockInfo
+}
+
+func (o *withCodeBlockInfo) SetConfig(c *renderer.Config) {
+	c.Options[optCodeBlockInfo] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withCodeBlockInfo) SetMarkdownOption(c *Config) {
+	c.CodeBlockInfo = o.value
+}
+
+// WithCodeBlockInfo is a functional option that sets the info string of fenced code blocks rendered
+// from indented code blocks. This setting has no effect unless the code block style is set to
+// CodeBlockStyleFenced.
+func WithCodeBlockInfo(info CodeBlockInfo) interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeBlockInfo{info}
+}
+
+// ============================================================================
+// CodeFormatters Option
+// ============================================================================
+
+// optCodeFormatters is an option name used in WithCodeFormatters
+const optCodeFormatters renderer.OptionName = "CodeFormatters"
+
+// CodeFormatters maps the languages of fenced code blocks, which are the first words of their info
+// strings, to the formatters of their code.
+type CodeFormatters map[string]CodeFormatter
+
+type withCodeFormatters struct {
+	value CodeFormatters
+}
+
+func (o *withCodeFormatters) SetConfig(c *renderer.Config) {
+	c.Options[optCodeFormatters] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withCodeFormatters) SetMarkdownOption(c *Config) {
+	c.CodeFormatters = o.value
+}
+
+// WithCodeFormatters is a functional option that sets the formatters of the code in fenced code
+// blocks, keyed by language. DefaultCodeFormatters returns formatters for Go, JSON, and markdown.
+func WithCodeFormatters(formatters CodeFormatters) interface {
+	renderer.Option
+	Option
+} {
+	return &withCodeFormatters{formatters}
+}
+
+// ============================================================================
+// DiagnosticHandler Option
+// ============================================================================
+
+// optDiagnosticHandler is an option name used in WithDiagnosticHandler
+const optDiagnosticHandler renderer.OptionName = "DiagnosticHandler"
+
+// DiagnosticHandler is called with problems found while rendering that don't stop the rendering,
+// such as a CodeFormatError for code that can't be formatted.
+type DiagnosticHandler func(err error)
+
+type withDiagnosticHandler struct {
+	value DiagnosticHandler
+}
+
+func (o *withDiagnosticHandler) SetConfig(c *renderer.Config) {
+	c.Options[optDiagnosticHandler] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withDiagnosticHandler) SetMarkdownOption(c *Config) {
+	c.DiagnosticHandler = o.value
+}
+
+// WithDiagnosticHandler is a functional option that sets the function called with problems found
+// while rendering that don't stop the rendering.
+func WithDiagnosticHandler(handler DiagnosticHandler) interface {
+	renderer.Option
+	Option
+} {
+	return &withDiagnosticHandler{handler}
+}
+
+// ============================================================================
+// HardLineBreakStyle Option
+// ============================================================================
+
+// optHardLineBreakStyle is an option name used in WithHardLineBreakStyle
+const optHardLineBreakStyle renderer.OptionName = "HardLineBreakStyle"
+
+// HardLineBreakStyle is an enum expressing how hard line breaks should be rendered.
+type HardLineBreakStyle int
+
+const (
+	// HardLineBreakStyleBackslash ends lines with a backslash. This is the default and zero value.
+	// Ex: foo\
+	//     bar
+	HardLineBreakStyleBackslash = iota
+	// HardLineBreakStyleSpaces ends lines with two spaces.
+	HardLineBreakStyleSpaces
+	// HardLineBreakStylePreserve keeps the backslash or spaces that each hard line break was
+	// written with in the source.
+	HardLineBreakStylePreserve
+	// HardLineBreakStyleHTML ends lines with a <br> tag, for output whose trailing whitespace may be
+	// stripped. Lines ending with a <br> tag are parsed as soft line breaks after raw HTML.
+	// Ex: foo<br>
+	//     bar
+	HardLineBreakStyleHTML
+)
+
+type withHardLineBreakStyle struct {
+	value HardLineBreakStyle
+}
+
+func (o *withHardLineBreakStyle) SetConfig(c *renderer.Config) {
+	c.Options[optHardLineBreakStyle] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withHardLineBreakStyle) SetMarkdownOption(c *Config) {
+	c.HardLineBreakStyle = o.value
+}
+
+// WithHardLineBreakStyle is a functional option that sets how hard line breaks are rendered.
+func WithHardLineBreakStyle(style HardLineBreakStyle) interface {
+	renderer.Option
+	Option
+} {
+	return &withHardLineBreakStyle{style}
+}
+
+// ============================================================================
+// LineEnding Option
+// ============================================================================
+
+// optLineEnding is an option name used in WithLineEnding
+const optLineEnding renderer.OptionName = "LineEnding"
+
+// LineEnding is an enum expressing how lines should be ended. Line endings inside code and HTML
+// blocks are converted too, so the whole document uses the same line ending.
+type LineEnding int
+
+const (
+	// LineEndingLF ends lines with a line feed. This is the default and zero value.
+	LineEndingLF = iota
+	// LineEndingCRLF ends lines with a carriage return and a line feed.
+	LineEndingCRLF
+	// LineEndingDetect ends lines with the line ending of the first line of the source, or a line
+	// feed if the source is a single line.
+	LineEndingDetect
+)
+
+type withLineEnding struct {
+	value LineEnding
+}
+
+func (o *withLineEnding) SetConfig(c *renderer.Config) {
+	c.Options[optLineEnding] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withLineEnding) SetMarkdownOption(c *Config) {
+	c.LineEnding = o.value
+}
+
+// WithLineEnding is a functional option that sets how lines are ended.
+func WithLineEnding(style LineEnding) interface {
+	renderer.Option
+	Option
+} {
+	return &withLineEnding{style}
+}
+
+// ============================================================================
+// FinalNewline Option
+// ============================================================================
+
+// optFinalNewline is an option name used in WithFinalNewline
+const optFinalNewline renderer.OptionName = "FinalNewline"
+
+// FinalNewline is an enum expressing how the last line of the document should be ended.
+type FinalNewline int
+
+const (
+	// FinalNewlineEnsure ends documents that aren't empty with exactly one line ending. This is the
+	// default and zero value.
+	FinalNewlineEnsure = iota
+	// FinalNewlinePreserve ends the document with a line ending only if the source ends with one.
+	FinalNewlinePreserve
+)
+
+type withFinalNewline struct {
+	value FinalNewline
+}
+
+func (o *withFinalNewline) SetConfig(c *renderer.Config) {
+	c.Options[optFinalNewline] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withFinalNewline) SetMarkdownOption(c *Config) {
+	c.FinalNewline = o.value
+}
+
+// WithFinalNewline is a functional option that sets how the last line of the document is ended.
+func WithFinalNewline(style FinalNewline) interface {
+	renderer.Option
+	Option
+} {
+	return &withFinalNewline{style}
+}
+
+// ============================================================================
+// ByteOrderMark Option
+// ============================================================================
+
+// optByteOrderMark is an option name used in WithByteOrderMark
+const optByteOrderMark renderer.OptionName = "ByteOrderMark"
+
+// ByteOrderMark is an enum expressing what to do with a UTF-8 byte order mark at the start of the
+// source. goldmark parses the byte order mark as text, so it is moved out of the rendered text and
+// the text after it is rendered as if it started the document.
+type ByteOrderMark int
+
+const (
+	// ByteOrderMarkPreserve starts the document with a byte order mark if the source starts with
+	// one. This is the default and zero value.
+	ByteOrderMarkPreserve = iota
+	// ByteOrderMarkStrip never starts the document with a byte order mark.
+	ByteOrderMarkStrip
+)
+
+type withByteOrderMark struct {
+	value ByteOrderMark
+}
+
+func (o *withByteOrderMark) SetConfig(c *renderer.Config) {
+	c.Options[optByteOrderMark] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withByteOrderMark) SetMarkdownOption(c *Config) {
+	c.ByteOrderMark = o.value
+}
+
+// WithByteOrderMark is a functional option that sets what to do with a byte order mark at the start
+// of the source.
+func WithByteOrderMark(style ByteOrderMark) interface {
+	renderer.Option
+	Option
+} {
+	return &withByteOrderMark{style}
+}
+
+// ============================================================================
+// MaxBlankLines Option
+// ============================================================================
+
+// optMaxBlankLines is an option name used in WithMaxBlankLines
+const optMaxBlankLines renderer.OptionName = "MaxBlankLines"
+
+// MaxBlankLines configures the maximum number of consecutive blank lines kept between blocks that
+// are separated by blank lines in the source.
+type MaxBlankLines int
+
+const (
+	// MaxBlankLinesMinimum is the minimum number of blank lines between blocks that are separated
+	// by blank lines. This is the default. Any numbers less than this minimum are converted to the
+	// minimum.
+	MaxBlankLinesMinimum = 1
+)
+
+type withMaxBlankLines struct {
+	value MaxBlankLines
+}
+
+func (o *withMaxBlankLines) SetConfig(c *renderer.Config) {
+	c.Options[optMaxBlankLines] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withMaxBlankLines) SetMarkdownOption(c *Config) {
+	c.MaxBlankLines = o.value
+}
+
+// WithMaxBlankLines is a functional option that sets the maximum number of consecutive blank lines
+// between blocks.
+func WithMaxBlankLines(lines MaxBlankLines) interface {
+	renderer.Option
+	Option
+} {
+	return &withMaxBlankLines{lines}
+}
+
+// ============================================================================
+// BlockSpacing Option
+// ============================================================================
+
+// optBlockSpacing is an option name used in WithBlockSpacing
+const optBlockSpacing renderer.OptionName = "BlockSpacing"
+
+// BlockSpacing is an enum expressing when blocks should be separated by blank lines. Blank lines
+// are always added where blocks would otherwise be parsed differently, like between two paragraphs.
+type BlockSpacing int
+
+const (
+	// BlockSpacingPreserve separates blocks with blank lines where they are in the source. This is
+	// the default and zero value.
+	BlockSpacingPreserve = iota
+	// BlockSpacingSeparated also separates headings, code blocks and lists from the blocks before
+	// and after them, except inside tight lists, where blank lines would make the list loose.
+	// Ex: "foo\n# bar\n- baz" is "foo\n\n# bar\n\n- baz"
+	BlockSpacingSeparated
+)
+
+type withBlockSpacing struct {
+	value BlockSpacing
+}
+
+func (o *withBlockSpacing) SetConfig(c *renderer.Config) {
+	c.Options[optBlockSpacing] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withBlockSpacing) SetMarkdownOption(c *Config) {
+	c.BlockSpacing = o.value
+}
+
+// WithBlockSpacing is a functional option that sets when blocks are separated by blank lines.
+func WithBlockSpacing(style BlockSpacing) interface {
+	renderer.Option
+	Option
+} {
+	return &withBlockSpacing{style}
+}
+
+// ============================================================================
+// ListSpacing Option
+// ============================================================================
+
+// optListSpacing is an option name used in WithListSpacing
+const optListSpacing renderer.OptionName = "ListSpacing"
+
+// ListSpacing is an enum expressing whether lists should be rendered tight, without blank lines
+// between their items and the blocks in their items, or loose, with blank lines between them.
+type ListSpacing int
+
+const (
+	// ListSpacingPreserve separates list items and their blocks with blank lines where they are in
+	// the source. This is the default and zero value.
+	ListSpacingPreserve = iota
+	// ListSpacingConsistent renders each list tight or loose throughout, depending on whether it
+	// was parsed as tight or loose, so one blank line doesn't leave a list partly loose.
+	// Ex: "- a\n- b\n\n- c" is "- a\n\n- b\n\n- c"
+	ListSpacingConsistent
+	// ListSpacingTight renders lists tight. Lists with items whose blocks can only be separated by
+	// blank lines, like two paragraphs, can't be tight and are rendered loose throughout.
+	ListSpacingTight
+	// ListSpacingLoose renders lists loose.
+	ListSpacingLoose
+)
+
+type withListSpacing struct {
+	value ListSpacing
+}
+
+func (o *withListSpacing) SetConfig(c *renderer.Config) {
+	c.Options[optListSpacing] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withListSpacing) SetMarkdownOption(c *Config) {
+	c.ListSpacing = o.value
+}
+
+// WithListSpacing is a functional option that sets whether lists are rendered tight or loose.
+func WithListSpacing(style ListSpacing) interface {
+	renderer.Option
+	Option
+} {
+	return &withListSpacing{style}
+}
+
+// ============================================================================
+// UnsupportedNodeHandling Option
+// ============================================================================
+
+// optUnsupportedNodeHandling is an option name used in WithUnsupportedNodeHandling
+const optUnsupportedNodeHandling renderer.OptionName = "UnsupportedNodeHandling"
+
+// UnsupportedNodeHandling is an enum expressing how nodes of kinds without a node renderer func,
+// like the nodes of third-party extensions, should be rendered.
+type UnsupportedNodeHandling int
+
+const (
+	// UnsupportedNodeHandlingVerbatim writes the source of the node as it is. This is the default
+	// and zero value. Blocks are written from their lines, and inlines from the source between the
+	// text around them. Nodes whose source can't be found have their children rendered instead.
+	UnsupportedNodeHandlingVerbatim = iota
+	// UnsupportedNodeHandlingSkip leaves the node and its children out of the output.
+	UnsupportedNodeHandlingSkip
+	// UnsupportedNodeHandlingError stops rendering, and makes Render return an *ErrUnsupportedNode.
+	UnsupportedNodeHandlingError
+)
+
+type withUnsupportedNodeHandling struct {
+	value UnsupportedNodeHandling
+}
+
+func (o *withUnsupportedNodeHandling) SetConfig(c *renderer.Config) {
+	c.Options[optUnsupportedNodeHandling] = o.value
+}
+
+// SetMarkdownOption implements renderer.Option
+func (o *withUnsupportedNodeHandling) SetMarkdownOption(c *Config) {
+	c.UnsupportedNodeHandling = o.value
+}
+
+// WithUnsupportedNodeHandling is a functional option that sets how nodes of kinds without a node
+// renderer func are rendered.
+func WithUnsupportedNodeHandling(handling UnsupportedNodeHandling) interface {
+	renderer.Option
+	Option
+} {
+	return &withUnsupportedNodeHandling{handling}
+}
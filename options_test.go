@@ -29,6 +29,33 @@ func TestRendererOptions(t *testing.T) {
 				WithThematicBreakLength(ThematicBreakLengthMinimum),
 				WithNestedListLength(NestedListLengthMinimum),
 				WithTypographerSubstitutions(false),
+				WithStrikethroughStyle(StrikethroughStyleDoubleTilde),
+				WithTaskCheckBoxStyle(TaskCheckBoxStyleLowercase),
+				WithFootnoteLabelStyle(FootnoteLabelStyleOriginal),
+				WithFootnotePlacement(FootnotePlacementDocumentEnd),
+				WithLinkReferenceDefinitionPlacement(LinkReferenceDefinitionPlacementPreserve),
+				WithLinkStyle(LinkStylePreserve),
+				WithLinkLabelStyle(LinkLabelStyleText),
+				WithLineWidth(LineWidthDefault),
+				WithProseWrap(ProseWrapPreserve),
+				WithEmphasisStyle(EmphasisStyleAsterisk),
+				WithStrongStyle(StrongStyleAsterisk),
+				WithBulletListMarker(BulletListMarkerPreserve),
+				WithOrderedListNumbering(OrderedListNumberingIncrementing),
+				WithOrderedListDelimiter(OrderedListDelimiterPreserve),
+				WithCodeFenceStyle(CodeFenceStyleBacktick),
+				WithCodeBlockStyle(CodeBlockStylePreserve),
+				WithCodeBlockInfo(""),
+				WithCodeFormatters(nil),
+				WithDiagnosticHandler(nil),
+				WithHardLineBreakStyle(HardLineBreakStyleBackslash),
+				WithLineEnding(LineEndingLF),
+				WithFinalNewline(FinalNewlineEnsure),
+				WithByteOrderMark(ByteOrderMarkPreserve),
+				WithMaxBlankLines(MaxBlankLinesMinimum),
+				WithBlockSpacing(BlockSpacingPreserve),
+				WithListSpacing(ListSpacingPreserve),
+				WithUnsupportedNodeHandling(UnsupportedNodeHandlingVerbatim),
 			},
 			NewConfig(),
 		},
@@ -6,12 +6,19 @@ import (
 	"fmt"
 	"io"
 	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
 	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 )
 
 // NewRenderer returns a new markdown Renderer that is configured by default values.
@@ -21,6 +28,7 @@ func NewRenderer(options ...Option) *Renderer {
 		rc:                   renderContext{},
 		maxKind:              20, // a random number slightly larger than the number of default ast kinds
 		nodeRendererFuncsTmp: map[ast.NodeKind]renderer.NodeRendererFunc{},
+		nodeRendererWrappers: map[ast.NodeKind][]NodeRendererWrapper{},
 	}
 	for _, opt := range options {
 		opt.SetMarkdownOption(r.config)
@@ -30,9 +38,12 @@ func NewRenderer(options ...Option) *Renderer {
 
 // Renderer is an implementation of renderer.Renderer that renders nodes as Markdown
 type Renderer struct {
-	config               *Config
-	rc                   renderContext
+	config *Config
+	rc     renderContext
+	// parser is the parser of the goldmark.Markdown that the renderer was added to as an extension
+	parser               parser.Parser
 	nodeRendererFuncsTmp map[ast.NodeKind]renderer.NodeRendererFunc
+	nodeRendererWrappers map[ast.NodeKind][]NodeRendererWrapper
 	maxKind              int
 	nodeRendererFuncs    []nodeRenderer
 	initSync             sync.Once
@@ -56,10 +67,29 @@ func (r *Renderer) AddOptions(opts ...renderer.Option) {
 	for i := l - 1; i >= 0; i-- {
 		v := config.NodeRenderers[i]
 		nr, _ := v.Value.(renderer.NodeRenderer)
+		// Extensions like goldmark's GFM tables register their own HTML renderers, which would
+		// replace the markdown renderers built into this package.
+		if isExtensionHTMLRenderer(nr) {
+			continue
+		}
 		nr.RegisterFuncs(r)
 	}
 }
 
+// isExtensionHTMLRenderer returns true if the given node renderer is one of goldmark's extension
+// HTML renderers whose node kinds are rendered by this package.
+func isExtensionHTMLRenderer(nr renderer.NodeRenderer) bool {
+	switch nr.(type) {
+	case *extension.TableHTMLRenderer,
+		*extension.StrikethroughHTMLRenderer,
+		*extension.TaskCheckBoxHTMLRenderer,
+		*extension.FootnoteHTMLRenderer,
+		*extension.DefinitionListHTMLRenderer:
+		return true
+	}
+	return false
+}
+
 func (r *Renderer) Register(kind ast.NodeKind, fun renderer.NodeRendererFunc) {
 	r.nodeRendererFuncsTmp[kind] = fun
 	if int(kind) > r.maxKind {
@@ -67,53 +97,222 @@ func (r *Renderer) Register(kind ast.NodeKind, fun renderer.NodeRendererFunc) {
 	}
 }
 
+// A NodeRendererWrapper returns a node renderer func that adds behavior around next, the node
+// renderer func it wraps. The node renderer funcs built into this package write to the renderer's
+// writer, which is the writer passed to the wrapping func, and ignore the writer passed to them.
+type NodeRendererWrapper func(next renderer.NodeRendererFunc) renderer.NodeRendererFunc
+
+// A NodeRendererWrapperRegisterer registers wrappers around node renderer funcs. The Renderer
+// implements it, so the RegisterFuncs method of a renderer.NodeRenderer can type-assert its
+// registerer to add wrappers.
+type NodeRendererWrapperRegisterer interface {
+	RegisterWrapper(kind ast.NodeKind, wrapper NodeRendererWrapper)
+}
+
+var _ NodeRendererWrapperRegisterer = &Renderer{}
+
+// RegisterWrapper wraps the node renderer func for nodes of the given kind, which is the func built
+// into this package or the one given to Register, so that behavior can be added around it without
+// reimplementing it. Wrappers registered later wrap the ones registered before them, including
+// wrappers registered after the first render, which take effect from the next render.
+func (r *Renderer) RegisterWrapper(kind ast.NodeKind, wrapper NodeRendererWrapper) {
+	if r.nodeRendererFuncs != nil {
+		// The node renderer funcs were already initialized by the first render
+		r.wrapNodeRenderer(kind, wrapper)
+		return
+	}
+	r.nodeRendererWrappers[kind] = append(r.nodeRendererWrappers[kind], wrapper)
+	if int(kind) > r.maxKind {
+		r.maxKind = int(kind)
+	}
+}
+
+// wrapNodeRenderer replaces the node renderer func for nodes of the given kind with the result of
+// applying the given wrappers to it in order.
+func (r *Renderer) wrapNodeRenderer(kind ast.NodeKind, wrappers ...NodeRendererWrapper) {
+	render := r.nodeRenderer(kind)
+	if render == nil {
+		render = r.renderUnsupportedNode
+	}
+	fun := r.untransform(render)
+	for _, wrap := range wrappers {
+		fun = wrap(fun)
+	}
+	if int(kind) >= len(r.nodeRendererFuncs) {
+		r.nodeRendererFuncs = append(r.nodeRendererFuncs, make([]nodeRenderer, int(kind)+1-len(r.nodeRendererFuncs))...)
+	}
+	r.nodeRendererFuncs[kind] = r.transform(fun)
+}
+
 // Render implements renderer.Renderer.Render
 func (r *Renderer) Render(w io.Writer, source []byte, n ast.Node) error {
 	r.rc = newRenderContext(w, source, r.config)
 	r.initSync.Do(func() {
-		r.nodeRendererFuncs = make([]nodeRenderer, r.maxKind+1)
-		// add default functions
-		// blocks
-		r.nodeRendererFuncs[ast.KindDocument] = r.renderBlockSeparator
-		r.nodeRendererFuncs[ast.KindHeading] = r.chainRenderers(r.renderBlockSeparator, r.renderHeading)
-		r.nodeRendererFuncs[ast.KindBlockquote] = r.chainRenderers(r.renderBlockSeparator, r.renderBlockquote)
-		r.nodeRendererFuncs[ast.KindCodeBlock] = r.chainRenderers(r.renderBlockSeparator, r.renderCodeBlock)
-		r.nodeRendererFuncs[ast.KindFencedCodeBlock] = r.chainRenderers(r.renderBlockSeparator, r.renderFencedCodeBlock)
-		r.nodeRendererFuncs[ast.KindHTMLBlock] = r.chainRenderers(r.renderBlockSeparator, r.renderHTMLBlock)
-		r.nodeRendererFuncs[ast.KindList] = r.chainRenderers(r.renderBlockSeparator, r.renderList)
-		r.nodeRendererFuncs[ast.KindListItem] = r.chainRenderers(r.renderBlockSeparator, r.renderListItem)
-		r.nodeRendererFuncs[ast.KindParagraph] = r.chainRenderers(r.renderBlockSeparator, r.renderParagraph)
-		r.nodeRendererFuncs[ast.KindTextBlock] = r.renderBlockSeparator
-		r.nodeRendererFuncs[ast.KindThematicBreak] = r.chainRenderers(r.renderBlockSeparator, r.renderThematicBreak)
-
-		// inlines
-		r.nodeRendererFuncs[ast.KindAutoLink] = r.renderAutoLink
-		r.nodeRendererFuncs[ast.KindCodeSpan] = r.renderCodeSpan
-		r.nodeRendererFuncs[ast.KindEmphasis] = r.renderEmphasis
-		r.nodeRendererFuncs[ast.KindImage] = r.renderImage
-		r.nodeRendererFuncs[ast.KindLink] = r.renderLink
-		r.nodeRendererFuncs[ast.KindRawHTML] = r.renderRawHTML
-		r.nodeRendererFuncs[ast.KindText] = r.renderText
-		r.nodeRendererFuncs[ast.KindString] = r.renderString
+		// default functions
+		defaultFuncs := map[ast.NodeKind]nodeRenderer{
+			// blocks
+			ast.KindDocument:        r.chainRenderers(r.renderBlockSeparator, r.renderDocument),
+			ast.KindHeading:         r.chainRenderers(r.renderSectionEnd, r.renderBlockSeparator, r.renderHeading),
+			ast.KindBlockquote:      r.chainRenderers(r.renderBlockSeparator, r.renderBlockquote),
+			ast.KindCodeBlock:       r.chainRenderers(r.renderBlockSeparator, r.renderCodeBlock),
+			ast.KindFencedCodeBlock: r.chainRenderers(r.renderBlockSeparator, r.renderFencedCodeBlock),
+			ast.KindHTMLBlock:       r.chainRenderers(r.renderBlockSeparator, r.renderHTMLBlock),
+			ast.KindList:            r.chainRenderers(r.renderBlockSeparator, r.renderList),
+			ast.KindListItem:        r.chainRenderers(r.renderBlockSeparator, r.renderListItem),
+			ast.KindParagraph:       r.chainRenderers(r.renderBlockSeparator, r.renderProse),
+			ast.KindTextBlock:       r.chainRenderers(r.renderBlockSeparator, r.renderProse),
+			ast.KindThematicBreak:   r.chainRenderers(r.renderBlockSeparator, r.renderThematicBreak),
+
+			// inlines
+			ast.KindAutoLink: r.renderAutoLink,
+			ast.KindCodeSpan: r.renderCodeSpan,
+			ast.KindEmphasis: r.renderEmphasis,
+			ast.KindImage:    r.renderImage,
+			ast.KindLink:     r.renderLink,
+			ast.KindRawHTML:  r.renderRawHTML,
+			ast.KindText:     r.renderText,
+			ast.KindString:   r.renderString,
+
+			// GFM extension inlines
+			east.KindStrikethrough: r.renderStrikethrough,
+			east.KindTaskCheckBox:  r.renderTaskCheckBox,
+
+			// footnote extension
+			east.KindFootnoteLink:     r.renderFootnoteLink,
+			east.KindFootnoteBacklink: r.renderFootnoteBacklink,
+			east.KindFootnote:         r.renderFootnote,
+			east.KindFootnoteList:     r.renderFootnoteList,
 
+			// GFM extension blocks
+			east.KindTable:       r.chainRenderers(r.renderBlockSeparator, r.renderTable),
+			east.KindTableHeader: r.renderTableRow,
+			east.KindTableRow:    r.renderTableRow,
+			east.KindTableCell:   r.renderTableCell,
+
+			// definition list extension
+			east.KindDefinitionList:        r.renderBlockSeparator,
+			east.KindDefinitionTerm:        r.renderBlockSeparator,
+			east.KindDefinitionDescription: r.chainRenderers(r.renderBlockSeparator, r.renderDefinitionDescription),
+
+			// link reference definitions kept by this package's parser options
+			KindLinkReferenceDefinition: r.renderLinkReferenceDefinition,
+		}
+		for kind := range defaultFuncs {
+			r.maxKind = max(r.maxKind, int(kind))
+		}
+
+		r.nodeRendererFuncs = make([]nodeRenderer, r.maxKind+1)
+		for kind, fun := range defaultFuncs {
+			r.nodeRendererFuncs[kind] = fun
+		}
 		for kind, fun := range r.nodeRendererFuncsTmp {
-			r.nodeRendererFuncs[kind] = r.transform(fun)
+			if fun != nil {
+				r.nodeRendererFuncs[kind] = r.transform(fun)
+			}
 		}
 		r.nodeRendererFuncsTmp = nil
+		for kind, wrappers := range r.nodeRendererWrappers {
+			r.wrapNodeRenderer(kind, wrappers...)
+		}
+		r.nodeRendererWrappers = nil
 	})
-	return ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
-		return r.nodeRendererFuncs[n.Kind()](n, entering), r.rc.writer.Err()
-	})
+	_ = ast.Walk(n, r.walk)
+	if r.rc.err != nil {
+		return r.rc.err
+	}
+	// Rendering may have been stopped in the middle of a line
+	r.rc.writer.FlushLine()
+	if err := r.rc.writer.Err(); err != nil {
+		return err
+	}
+	return r.rc.document.Close()
 }
 
-// transform wraps a renderer.NodeRendererFunc to match the nodeRenderer function signature
+// walk is an ast.Walker that renders the given node using the registered node renderer funcs.
+// Errors and stops are kept in the render context, so that they also stop the rendering of nodes
+// that walk the nodes they contain themselves.
+func (r *Renderer) walk(n ast.Node, entering bool) (ast.WalkStatus, error) {
+	if r.rc.err != nil || r.rc.stopped {
+		return ast.WalkStop, r.rc.err
+	}
+	render := r.nodeRenderer(n.Kind())
+	if render == nil {
+		render = r.renderUnsupportedNode
+	}
+	status := render(n, entering)
+	if r.rc.err == nil {
+		r.rc.err = r.rc.writer.Err()
+	}
+	r.rc.stopped = status == ast.WalkStop
+	return status, r.rc.err
+}
+
+// transform wraps a renderer.NodeRendererFunc to match the nodeRenderer function signature. Errors
+// returned by the func stop the rendering, and are returned by Render as a *NodeRenderError.
 func (r *Renderer) transform(fn renderer.NodeRendererFunc) nodeRenderer {
 	return func(n ast.Node, entering bool) ast.WalkStatus {
-		status, _ := fn(r.rc.writer, r.rc.source, n, entering)
+		status, err := fn(r.rc.writer, r.rc.source, n, entering)
+		if err != nil {
+			// Errors that already stopped the rendering are passed on by wrappers
+			if r.rc.err == nil {
+				r.rc.err = r.nodeRenderError(n, err)
+			}
+			return ast.WalkStop
+		}
 		return status
 	}
 }
 
+// untransform wraps a nodeRenderer to match the renderer.NodeRendererFunc signature, so it can be
+// wrapped by a NodeRendererWrapper. The error that stopped the rendering, if any, is returned.
+func (r *Renderer) untransform(render nodeRenderer) renderer.NodeRendererFunc {
+	return func(_ util.BufWriter, _ []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		return render(n, entering), r.rc.err
+	}
+}
+
+// A NodeRenderError is returned by Render when a registered renderer.NodeRendererFunc returns an
+// error.
+type NodeRenderError struct {
+	// Kind is the kind of the node being rendered
+	Kind ast.NodeKind
+	// Line is the line number of the node in the source, starting at 1, or 0 if it isn't known
+	Line int
+	// Err is the error returned by the node renderer func
+	Err error
+}
+
+// Error implements error.Error
+func (e *NodeRenderError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("rendering %s node: %v", e.Kind, e.Err)
+	}
+	return fmt.Sprintf("line %d: rendering %s node: %v", e.Line, e.Kind, e.Err)
+}
+
+// Unwrap returns the error returned by the node renderer func.
+func (e *NodeRenderError) Unwrap() error {
+	return e.Err
+}
+
+// nodeRenderError wraps an error returned by the node renderer func of the given node.
+func (r *Renderer) nodeRenderError(n ast.Node, err error) *NodeRenderError {
+	e := &NodeRenderError{Kind: n.Kind(), Err: err}
+	start, ok := blockStart(n)
+	if n.Type() == ast.TypeInline {
+		start, _, ok = textSpan(n)
+	}
+	if ok && start <= len(r.rc.source) {
+		e.Line = lineNumber(r.rc.source, start)
+	}
+	return e
+}
+
+// lineNumber returns the number of the line in source that contains the given offset, starting at 1.
+func lineNumber(source []byte, offset int) int {
+	return bytes.Count(source[:offset], []byte{lineDelim}) + 1
+}
+
 // nodeRenderer is a markdown node renderer func.
 type nodeRenderer func(ast.Node, bool) ast.WalkStatus
 
@@ -133,9 +332,12 @@ func (r *Renderer) chainRenderers(renderers ...nodeRenderer) nodeRenderer {
 
 func (r *Renderer) renderBlockSeparator(node ast.Node, entering bool) ast.WalkStatus {
 	if entering {
-		// Add blank previous line if applicable
-		if node.PreviousSibling() != nil && node.HasBlankPreviousLines() {
-			r.rc.writer.EndLine()
+		// Add blank previous lines if applicable
+		if prev, blank := r.previousSibling(node); prev != nil && blank {
+			lines := min(max(r.sourceBlankLines(node), 1), max(int(r.config.MaxBlankLines), MaxBlankLinesMinimum))
+			for range lines {
+				r.rc.writer.EndLine()
+			}
 		}
 	} else {
 		// Flush line buffer to complete line written by previous block
@@ -144,17 +346,241 @@ func (r *Renderer) renderBlockSeparator(node ast.Node, entering bool) ast.WalkSt
 	return ast.WalkContinue
 }
 
-func (r *Renderer) renderParagraph(node ast.Node, entering bool) ast.WalkStatus {
-	if entering {
-		// Special handling of paragraphs inside blockquotes, because they always set HasBlankPreviousLines to false
-		prev := node.PreviousSibling()
-		if prev != nil && ast.IsParagraph(prev) && node.Parent().Kind() == ast.KindBlockquote {
-			r.rc.writer.EndLine()
+// previousSibling returns the previous sibling of node that is rendered in place, and whether the
+// two are separated by a blank line.
+func (r *Renderer) previousSibling(node ast.Node) (ast.Node, bool) {
+	prev := node.PreviousSibling()
+	// Blocks in blockquotes don't record blank lines before them, so they're found in the source
+	blank := node.HasBlankPreviousLines() || r.sourceBlankLines(node) > 0
+	for prev != nil && r.isMovedLinkReferenceDefinition(prev) {
+		// Keep the blocks around a moved definition apart, as they were in the source
+		prev = prev.PreviousSibling()
+		blank = true
+	}
+	if prev == nil {
+		return nil, blank
+	}
+	if list, ok := node.Parent().(*ast.List); ok && !list.IsTight &&
+		(r.hasMovedLinkReferenceDefinition(prev) || r.hasMovedLinkReferenceDefinition(node)) {
+		// The blank lines that made the list loose may have been around the moved definition
+		blank = true
+	}
+	if l, ok := r.parentList(node); ok && r.config.ListSpacing != ListSpacingPreserve {
+		blank = !l.tight
+	}
+	if r.config.BlockSpacing == BlockSpacingSeparated && (isSpacedBlock(prev) || isSpacedBlock(node)) {
+		if l, ok := r.parentList(node); !ok || !l.tight {
+			blank = true
+		}
+	}
+	return prev, blank || r.needsBlankLine(prev, node)
+}
+
+// parentList returns the context of the list that node is an item of, or is in an item of.
+func (r *Renderer) parentList(node ast.Node) (listContext, bool) {
+	if len(r.rc.lists) == 0 ||
+		(node.Kind() != ast.KindListItem && (node.Parent() == nil || node.Parent().Kind() != ast.KindListItem)) {
+		return listContext{}, false
+	}
+	return r.rc.lists[len(r.rc.lists)-1], true
+}
+
+// isSpacedBlock returns true if node is a block that BlockSpacingSeparated separates from the blocks
+// around it.
+func isSpacedBlock(node ast.Node) bool {
+	switch node.Kind() {
+	case ast.KindHeading, ast.KindCodeBlock, ast.KindFencedCodeBlock, ast.KindList:
+		return true
+	}
+	return false
+}
+
+// needsBlankLine returns true if node must be separated from the previous block by a blank line,
+// because it would otherwise continue the previous block or be parsed differently.
+func (r *Renderer) needsBlankLine(prev, node ast.Node) bool {
+	if html, ok := prev.(*ast.HTMLBlock); ok &&
+		(html.HTMLBlockType == ast.HTMLBlockType6 || html.HTMLBlockType == ast.HTMLBlockType7) {
+		// These HTML blocks end at a blank line
+		return true
+	}
+	if node.Kind() == east.KindTable || node.Kind() == east.KindDefinitionList {
+		// Tables and definition lists are transformed from paragraphs, which loses the paragraph's
+		// blank previous lines. They would otherwise be joined with a preceding paragraph.
+		return true
+	}
+	if node.Kind() == east.KindDefinitionTerm && prev.Kind() == east.KindDefinitionDescription {
+		// A term following a description would otherwise be a lazy continuation line of the
+		// description
+		return true
+	}
+	if node.Kind() == ast.KindCodeBlock && r.config.CodeBlockStyle != CodeBlockStyleFenced {
+		// Indented code can't interrupt a paragraph, and would continue a list item or indented code
+		return endsWithParagraph(prev) || prev.Kind() == ast.KindList || prev.Kind() == ast.KindCodeBlock
+	}
+	if !endsWithParagraph(prev) {
+		return false
+	}
+	// Only some blocks can interrupt a paragraph, and others would be parsed as part of it
+	switch n := node.(type) {
+	case *ast.ListItem, *ast.Blockquote, *ast.FencedCodeBlock:
+		return false
+	case *ast.Heading:
+		return r.isSetextHeading(n)
+	case *ast.ThematicBreak:
+		// A paragraph followed by a dashed thematic break is a setext heading
+		return r.config.ThematicBreakStyle == ThematicBreakStyleDashed
+	case *ast.HTMLBlock:
+		return n.HTMLBlockType == ast.HTMLBlockType7
+	case *ast.List:
+		start := n.Start
+		if r.config.OrderedListNumbering == OrderedListNumberingStartAtOne {
+			start = 1
 		}
+		// Lists that start with an empty item, or ordered lists that don't start at 1, can't
+		// interrupt a paragraph
+		return r.isEmptyContainer(n.FirstChild()) || (n.IsOrdered() && start != 1)
+	}
+	return true
+}
+
+// endsWithParagraph returns true if node is a paragraph, or a container block whose last block is a
+// paragraph, which can be continued by lazy continuation lines.
+func endsWithParagraph(node ast.Node) bool {
+	for node != nil {
+		switch node.Kind() {
+		case ast.KindParagraph, ast.KindTextBlock:
+			return true
+		case ast.KindBlockquote, ast.KindList, ast.KindListItem:
+			node = node.LastChild()
+		default:
+			return false
+		}
+	}
+	return false
+}
+
+// sourceBlankLines returns the number of blank lines before node in the source, or 0 if the start
+// of node can't be found. Lines holding only the markers of the blockquotes containing node are
+// blank too.
+func (r *Renderer) sourceBlankLines(node ast.Node) int {
+	start, ok := blockStart(node)
+	if !ok || start > len(r.rc.source) {
+		return 0
+	}
+	depth := 0
+	for p := node.Parent(); p != nil; p = p.Parent() {
+		if p.Kind() == ast.KindBlockquote {
+			depth++
+		}
+	}
+	source := r.rc.source
+	count := 0
+	// i is the offset of the line delimiter that ends the line being checked
+	for i := bytes.LastIndexByte(source[:start], lineDelim); i >= 0; count++ {
+		j := bytes.LastIndexByte(source[:i], lineDelim)
+		if !isBlankQuoteLine(source[j+1:i], depth) {
+			break
+		}
+		i = j
+	}
+	return count
+}
+
+// isBlankQuoteLine returns true if line only holds whitespace and up to depth blockquote markers.
+func isBlankQuoteLine(line []byte, depth int) bool {
+	for _, c := range line {
+		switch {
+		case c == '>' && depth > 0:
+			depth--
+		case c != ' ' && c != '\t' && c != '\r':
+			return false
+		}
+	}
+	return true
+}
+
+// blockStart returns the offset in the source of the first line of node, if it can be found. Most
+// blocks are found by the first line of their first block that has lines.
+func blockStart(node ast.Node) (int, bool) {
+	for n := node; n != nil && n.Type() == ast.TypeBlock; n = n.FirstChild() {
+		if fenced, ok := n.(*ast.FencedCodeBlock); ok {
+			// The lines of fenced code blocks start after the opening fence
+			if fenced.Info == nil {
+				return 0, false
+			}
+			return fenced.Info.Segment.Start, true
+		}
+		if lines := n.Lines(); lines.Len() > 0 {
+			return lines.At(0).Start, true
+		}
+	}
+	return 0, false
+}
+
+func (r *Renderer) renderDocument(node ast.Node, entering bool) ast.WalkStatus {
+	if !entering {
+		l := &r.rc.linkReferenceContext
+		r.renderLinkReferenceDefinitions(append(l.section, l.pending...))
+		l.section, l.pending = nil, nil
 	}
 	return ast.WalkContinue
 }
 
+// renderProse renders the inline content of paragraphs and text blocks, wrapping it as configured by
+// the ProseWrap option.
+func (r *Renderer) renderProse(node ast.Node, entering bool) ast.WalkStatus {
+	if r.config.ProseWrap == ProseWrapPreserve || node.Parent() == nil {
+		return ast.WalkContinue
+	}
+	if entering {
+		// Render the text up front, with soft line breaks replaced by spaces
+		p := &r.rc.proseContext
+		buf := bytes.Buffer{}
+		writer := r.rc.writer
+		r.rc.writer = newMarkdownWriter(&buf, r.config)
+		p.writer, p.buf, p.breaks = r.rc.writer, &buf, nil
+		for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+			_ = ast.Walk(c, r.walk)
+		}
+		r.rc.writer.FlushLine()
+		r.rc.writer = writer
+		text := bytes.TrimSuffix(buf.Bytes(), []byte{lineDelim})
+		breaks := p.breaks
+		*p = proseContext{}
+
+		// Text that was only partly rendered before rendering stopped is written as it is
+		if r.rc.err == nil && !r.rc.stopped {
+			switch r.config.ProseWrap {
+			case ProseWrapAlways:
+				text = wrapProse(text, breaks, func(line int) int {
+					return int(r.config.LineWidth) - r.rc.writer.prefixWidth(line)
+				})
+			case ProseWrapSentence:
+				text = breakSentences(text, breaks)
+			}
+		}
+		// Hard line breaks may end lines with spaces
+		r.rc.writer.BeginVerbatim()
+		r.rc.writer.WriteBytes(text)
+		r.rc.writer.EndVerbatim()
+	}
+	return ast.WalkSkipChildren
+}
+
+// writeProse writes the given inline text. When prose is being wrapped, the spaces in the text are
+// recorded as places where lines can be broken.
+func (r *Renderer) writeProse(text []byte) {
+	if p := &r.rc.proseContext; p.writer != nil && p.writer == r.rc.writer {
+		offset := p.buf.Len() + r.rc.writer.Buffered()
+		for i, c := range text {
+			if c == ' ' {
+				p.breaks = append(p.breaks, offset+i)
+			}
+		}
+	}
+	r.rc.writer.WriteBytes(text)
+}
+
 func (r *Renderer) renderAutoLink(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.AutoLink)
 	if entering {
@@ -170,6 +596,9 @@ func (r *Renderer) renderBlockquote(node ast.Node, entering bool) ast.WalkStatus
 	if entering {
 		r.rc.writer.PushPrefix([]byte("> "))
 	} else {
+		if r.isEmptyContainer(node) {
+			r.rc.writer.EndLine()
+		}
 		r.rc.writer.PopPrefix()
 	}
 	return ast.WalkContinue
@@ -177,21 +606,22 @@ func (r *Renderer) renderBlockquote(node ast.Node, entering bool) ast.WalkStatus
 
 func (r *Renderer) renderHeading(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.Heading)
-	// Empty headings or headings above level 2 can only be ATX
-	if !n.HasChildren() || n.Level > 2 {
-		return r.renderATXHeading(n, entering)
-	}
-	// Multiline headings can only be Setext
-	if n.Lines().Len() > 1 {
-		return r.renderSetextHeading(n, entering)
-	}
-	// Otherwise it's up to the configuration
-	if r.config.IsSetext() {
+	if r.isSetextHeading(n) {
 		return r.renderSetextHeading(n, entering)
 	}
 	return r.renderATXHeading(n, entering)
 }
 
+// isSetextHeading returns true if the given heading is rendered as a setext heading.
+func (r *Renderer) isSetextHeading(n *ast.Heading) bool {
+	// Empty headings or headings above level 2 can only be ATX
+	if !n.HasChildren() || n.Level > 2 {
+		return false
+	}
+	// Multiline headings can only be Setext. Otherwise it's up to the configuration
+	return n.Lines().Len() > 1 || r.config.IsSetext()
+}
+
 func (r *Renderer) renderATXHeading(node *ast.Heading, entering bool) ast.WalkStatus {
 	if entering {
 		r.rc.writer.WriteBytes(bytes.Repeat([]byte("#"), node.Level))
@@ -241,36 +671,122 @@ func (r *Renderer) renderThematicBreak(node ast.Node, entering bool) ast.WalkSta
 }
 
 func (r *Renderer) renderCodeBlock(node ast.Node, entering bool) ast.WalkStatus {
+	if r.config.CodeBlockStyle == CodeBlockStyleFenced {
+		return r.renderFence(node, []byte(r.config.CodeBlockInfo), entering)
+	}
+	return r.renderIndentedCode(node, entering)
+}
+
+func (r *Renderer) renderFencedCodeBlock(node ast.Node, entering bool) ast.WalkStatus {
+	n := node.(*ast.FencedCodeBlock)
+	if r.isIndentedCode(n) {
+		return r.renderIndentedCode(node, entering)
+	}
+	var info []byte
+	if n.Info != nil {
+		info = n.Info.Value(r.rc.source)
+	}
+	return r.renderFence(node, info, entering)
+}
+
+// renderIndentedCode renders the lines of a code block indented by four columns.
+func (r *Renderer) renderIndentedCode(node ast.Node, entering bool) ast.WalkStatus {
 	if entering {
-		r.rc.writer.PushPrefix(r.config.Bytes())
+		r.rc.writer.PushIndent(4)
+		r.rc.writer.BeginVerbatim()
 		r.renderLines(node, entering)
+		r.rc.writer.EndVerbatim()
 	} else {
 		r.rc.writer.PopPrefix()
 	}
 	return ast.WalkContinue
 }
 
-func (r *Renderer) renderFencedCodeBlock(node ast.Node, entering bool) ast.WalkStatus {
-	n := node.(*ast.FencedCodeBlock)
-	r.rc.writer.WriteBytes([]byte("```"))
+// renderFence renders the code of a code block between code fences, with the given info string.
+func (r *Renderer) renderFence(node ast.Node, info []byte, entering bool) ast.WalkStatus {
 	if entering {
-		if info := n.Info; info != nil {
-			r.rc.writer.WriteBytes(info.Value(r.rc.source))
-		}
+		code := r.formatCode(node.Lines(), info)
+		fence := r.codeFence(code, info)
+		r.rc.writer.WriteBytes(fence)
+		r.rc.writer.WriteBytes(info)
 		r.rc.writer.FlushLine()
-		r.renderLines(node, entering)
+		r.rc.writer.BeginVerbatim()
+		r.rc.writer.WriteBytes(code)
+		r.rc.writer.EndVerbatim()
+		r.rc.writer.WriteBytes(fence)
 	}
 	return ast.WalkContinue
 }
 
+// isIndentedCode returns true if the given node is a code block that is rendered as an indented
+// code block.
+func (r *Renderer) isIndentedCode(node ast.Node) bool {
+	switch n := node.(type) {
+	case *ast.CodeBlock:
+		return r.config.CodeBlockStyle != CodeBlockStyleFenced
+	case *ast.FencedCodeBlock:
+		return r.config.CodeBlockStyle == CodeBlockStyleIndented && n.Info == nil && r.canIndentCode(n)
+	}
+	return false
+}
+
+// canIndentCode returns true if the given fenced code block keeps its content and meaning when
+// rendered as an indented code block.
+func (r *Renderer) canIndentCode(n *ast.FencedCodeBlock) bool {
+	lines := n.Lines()
+	if lines.Len() == 0 {
+		return false
+	}
+	// Blank lines at the start and end of indented code blocks aren't part of the code
+	first, last := lines.At(0), lines.At(lines.Len()-1)
+	if util.IsBlank(first.Value(r.rc.source)) || util.IsBlank(last.Value(r.rc.source)) {
+		return false
+	}
+	// Indented code can't interrupt a paragraph, would continue a preceding list item or indented
+	// code block, and would be continued by a following indented code block.
+	if prev, blank := r.previousSibling(n); prev != nil &&
+		(!blank || prev.Kind() == ast.KindList || r.isIndentedCode(prev)) {
+		return false
+	}
+	next := n.NextSibling()
+	return next == nil || next.Kind() != ast.KindCodeBlock
+}
+
+// codeFence returns the fence for a fenced code block with the given code and info string. The
+// fence is longer than any run of the fence character that starts a line of the code, so that it
+// can't be closed by the code.
+func (r *Renderer) codeFence(code []byte, info []byte) []byte {
+	c := r.config.FenceChar()
+	if c == '`' && bytes.IndexByte(info, '`') >= 0 {
+		// The info string of a backtick fence can't contain backticks
+		c = '~'
+	}
+	length := 3
+	for _, line := range bytes.Split(code, []byte{lineDelim}) {
+		// Closing fences can be indented by up to three spaces
+		start := 0
+		for start < len(line) && start < 3 && line[start] == ' ' {
+			start++
+		}
+		end := start
+		for end < len(line) && line[end] == c {
+			end++
+		}
+		length = max(length, end-start+1)
+	}
+	return bytes.Repeat([]byte{c}, length)
+}
+
 func (r *Renderer) renderHTMLBlock(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.HTMLBlock)
 	if entering {
+		r.rc.writer.BeginVerbatim()
 		r.renderLines(node, entering)
 	} else {
 		if n.HasClosure() {
 			r.rc.writer.WriteLine(n.ClosureLine.Value(r.rc.source))
 		}
+		r.rc.writer.EndVerbatim()
 	}
 	return ast.WalkContinue
 }
@@ -278,9 +794,15 @@ func (r *Renderer) renderHTMLBlock(node ast.Node, entering bool) ast.WalkStatus
 func (r *Renderer) renderList(node ast.Node, entering bool) ast.WalkStatus {
 	if entering {
 		n := node.(*ast.List)
+		num := n.Start
+		if r.config.OrderedListNumbering == OrderedListNumberingStartAtOne {
+			num = 1
+		}
 		r.rc.lists = append(r.rc.lists, listContext{
-			list: n,
-			num:  n.Start,
+			list:   n,
+			num:    num,
+			marker: r.listMarker(n, len(r.rc.lists)),
+			tight:  r.isTightList(n),
 		})
 	} else {
 		r.rc.lists = r.rc.lists[:len(r.rc.lists)-1]
@@ -288,23 +810,139 @@ func (r *Renderer) renderList(node ast.Node, entering bool) ast.WalkStatus {
 	return ast.WalkContinue
 }
 
+// isTightList returns true if the given list is rendered tight according to the list spacing.
+func (r *Renderer) isTightList(n *ast.List) bool {
+	switch r.config.ListSpacing {
+	case ListSpacingTight:
+		// Lists can only be tight if none of the blocks in their items need blank lines between them
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			for c := item.FirstChild(); c != nil; c = c.NextSibling() {
+				prev := c.PreviousSibling()
+				for prev != nil && r.isMovedLinkReferenceDefinition(prev) {
+					prev = prev.PreviousSibling()
+				}
+				if prev != nil && r.needsBlankLine(prev, c) {
+					return false
+				}
+			}
+		}
+		return true
+	case ListSpacingLoose:
+		return false
+	}
+	return n.IsTight
+}
+
+// bulletListMarkers are the markers of bullet lists, in the order they are used by
+// BulletListMarkerAlternate.
+var bulletListMarkers = []byte{'-', '*', '+'}
+
+// orderedListDelimiters are the delimiters of ordered lists.
+var orderedListDelimiters = []byte{'.', ')'}
+
+// listMarker returns the marker to render the items of the given list with, which is the delimiter
+// after the number for ordered lists. depth is the number of lists that contain the list. A list
+// that directly follows another list with the same marker would be parsed as part of that list, so
+// it is given a different marker.
+func (r *Renderer) listMarker(n *ast.List, depth int) byte {
+	marker := n.Marker
+	markers := bulletListMarkers
+	if n.IsOrdered() {
+		markers = orderedListDelimiters
+		switch r.config.OrderedListDelimiter {
+		case OrderedListDelimiterPeriod:
+			marker = '.'
+		case OrderedListDelimiterParenthesis:
+			marker = ')'
+		}
+	} else {
+		switch r.config.BulletListMarker {
+		case BulletListMarkerDash:
+			marker = '-'
+		case BulletListMarkerAsterisk:
+			marker = '*'
+		case BulletListMarkerPlus:
+			marker = '+'
+		case BulletListMarkerAlternate:
+			marker = bulletListMarkers[depth%len(bulletListMarkers)]
+		}
+	}
+	if prev, ok := r.previousList(n); ok && prev.IsOrdered() == n.IsOrdered() {
+		prevMarker := r.listMarker(prev, depth)
+		for i := 0; marker == prevMarker; i++ {
+			marker = markers[i]
+		}
+	}
+	return marker
+}
+
+// listItemNumber returns the number to render the given ordered list item with, and advances the
+// number of the next item.
+func (r *Renderer) listItemNumber(node ast.Node) int {
+	l := &r.rc.lists[len(r.rc.lists)-1]
+	num := l.num
+	switch r.config.OrderedListNumbering {
+	case OrderedListNumberingAllOnes:
+		return l.list.Start
+	case OrderedListNumberingPreserve:
+		if source, ok := sourceListItemNumber(node, r.rc.source); ok {
+			num = source
+		}
+	}
+	l.num = num + 1
+	return num
+}
+
+// sourceListItemNumber returns the number that the given ordered list item was written with in the
+// source. List items don't have segments, so the number is found before the first line of the
+// paragraph that starts the item. Items that start with other blocks, or are empty, aren't
+// supported.
+func sourceListItemNumber(item ast.Node, source []byte) (int, bool) {
+	child := item.FirstChild()
+	if child == nil || (child.Kind() != ast.KindParagraph && child.Kind() != ast.KindTextBlock) ||
+		child.Lines().Len() == 0 {
+		return 0, false
+	}
+	i := child.Lines().At(0).Start - 1
+	for i >= 0 && (source[i] == ' ' || source[i] == '\t') {
+		i--
+	}
+	if i < 0 || (source[i] != '.' && source[i] != ')') {
+		return 0, false
+	}
+	end := i
+	for i > 0 && source[i-1] >= '0' && source[i-1] <= '9' {
+		i--
+	}
+	num, err := strconv.Atoi(string(source[i:end]))
+	return num, err == nil
+}
+
+// previousList returns the list rendered right before the given node, if any.
+func (r *Renderer) previousList(node ast.Node) (*ast.List, bool) {
+	prev, _ := r.previousSibling(node)
+	list, ok := prev.(*ast.List)
+	return list, ok
+}
+
 func (r *Renderer) renderListItem(node ast.Node, entering bool) ast.WalkStatus {
 	if entering {
 		var itemPrefix []byte
 		l := r.rc.lists[len(r.rc.lists)-1]
 
 		if l.list.IsOrdered() {
-			itemPrefix = append(itemPrefix, []byte(fmt.Sprint(l.num))...)
-			r.rc.lists[len(r.rc.lists)-1].num += 1
+			itemPrefix = append(itemPrefix, []byte(fmt.Sprint(r.listItemNumber(node)))...)
 		}
-		itemPrefix = append(itemPrefix, l.list.Marker, ' ')
+		itemPrefix = append(itemPrefix, l.marker, ' ')
 		// Prefix the current line with the item prefix
 		r.rc.writer.PushPrefix(itemPrefix, 0, 0)
-		// Prefix subsequent lines with padding the same length as the item prefix
+		// Indent subsequent lines by the width of the item prefix
 		indentLen := int(max(r.config.NestedListLength, NestedListLengthMinimum))
-		indent := bytes.Repeat([]byte{' '}, indentLen)
-		r.rc.writer.PushPrefix(bytes.Repeat(indent, len(itemPrefix)), 1)
+		r.rc.writer.PushIndent(indentLen*len(itemPrefix), 1)
 	} else {
+		if r.isEmptyContainer(node) {
+			r.rc.writer.EndLine()
+		}
 		r.rc.writer.PopPrefix()
 		r.rc.writer.PopPrefix()
 	}
@@ -323,26 +961,115 @@ func (r *Renderer) renderText(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.Text)
 	if entering {
 		text := n.Value(r.rc.source)
-
-		r.rc.writer.WriteBytes(text)
+		if n.Segment.Start == 0 {
+			// The byte order mark is written by the document writer
+			text = bytes.TrimPrefix(text, byteOrderMark)
+		}
+		if node.Parent() == nil || node.Parent().Kind() != ast.KindCodeSpan {
+			ctx := r.textContext(node, n.SoftLineBreak() || n.HardLineBreak())
+			if n.IsRaw() {
+				text = escapeInline(text, ctx, true)
+			}
+			// Text from the source keeps its escapes, but may start a line in a new context
+			text = escapeLineStart(text, ctx)
+			r.writeProse(text)
+		} else {
+			r.rc.writer.WriteBytes(text)
+		}
 		if n.SoftLineBreak() {
-			r.rc.writer.EndLine()
+			if r.rc.proseContext.writer != nil {
+				// Soft line breaks are placed by the prose wrapping
+				r.writeProse([]byte(" "))
+			} else {
+				r.rc.writer.EndLine()
+			}
 		} else if n.HardLineBreak() {
-			_, _ = r.rc.writer.WriteRune('\\')
-			r.rc.writer.EndLine()
+			r.renderHardLineBreak(n)
 		}
 	}
 	return ast.WalkContinue
 }
 
+// renderHardLineBreak ends the line of the given text with a hard line break.
+func (r *Renderer) renderHardLineBreak(n *ast.Text) {
+	style := r.config.HardLineBreakStyle
+	if style == HardLineBreakStylePreserve {
+		// The segment of the text ends before the backslash or spaces of the line break
+		style = HardLineBreakStyleBackslash
+		if stop := n.Segment.Stop; stop < len(r.rc.source) && r.rc.source[stop] == ' ' {
+			style = HardLineBreakStyleSpaces
+		}
+	}
+	switch style {
+	case HardLineBreakStyleSpaces:
+		r.rc.writer.BeginVerbatim()
+		r.rc.writer.WriteBytes([]byte("  "))
+		r.rc.writer.EndLine()
+		r.rc.writer.EndVerbatim()
+	case HardLineBreakStyleHTML:
+		r.rc.writer.WriteBytes([]byte("<br>"))
+		r.rc.writer.EndLine()
+	default:
+		_, _ = r.rc.writer.WriteRune('\\')
+		r.rc.writer.EndLine()
+	}
+}
+
 func (r *Renderer) renderString(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.String)
 	if entering {
-		r.rc.writer.WriteBytes(n.Value)
+		text := n.Value
+		// Code strings, like those from the typographer extension, are written without modification
+		if !n.IsCode() {
+			ctx := r.textContext(node, false)
+			text = escapeLineStart(escapeInline(text, ctx, n.IsRaw()), ctx)
+		}
+		r.writeProse(text)
 	}
 	return ast.WalkContinue
 }
 
+// textContext returns the context of the given inline text node in the output. lineBreak is true
+// if the text is followed by a line break.
+func (r *Renderer) textContext(node ast.Node, lineBreak bool) textContext {
+	ctx := textContext{
+		before:  r.rc.writer.lastRune(),
+		after:   utf8.RuneError,
+		lineEnd: lineBreak,
+	}
+	// Find the block containing the text, and whether the text is preceded by other inlines.
+	block := node
+	for ; block != nil && block.Type() != ast.TypeBlock; block = block.Parent() {
+		if block.PreviousSibling() != nil {
+			ctx.continuation = true
+		}
+	}
+	// Table cells can't contain blocks
+	ctx.lineStart = r.rc.writer.Buffered() == 0 && block != nil && block.Kind() != east.KindTableCell
+	if !ctx.lineStart {
+		ctx.continuation = false
+	}
+
+	if next := node.NextSibling(); lineBreak {
+		ctx.after = rune(lineDelim)
+	} else if next == nil && node.Parent() == block {
+		ctx.lineEnd = true
+		ctx.after = rune(lineDelim)
+	} else if next != nil {
+		var value []byte
+		switch next := next.(type) {
+		case *ast.Text:
+			value = next.Value(r.rc.source)
+		case *ast.String:
+			value = next.Value
+		}
+		if len(value) > 0 {
+			ctx.after, _ = utf8.DecodeRune(value)
+		}
+	}
+	return ctx
+}
+
 func (r *Renderer) renderSegments(segments *text.Segments, asLines bool) {
 	for i := 0; i < segments.Len(); i++ {
 		segment := segments.At(i)
@@ -364,7 +1091,7 @@ func (r *Renderer) renderLines(node ast.Node, entering bool) ast.WalkStatus {
 
 func (r *Renderer) renderLink(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.Link)
-	return r.renderLinkCommon(n.Title, n.Destination, entering)
+	return r.renderLinkCommon(n, n.Title, n.Destination, entering)
 }
 
 func (r *Renderer) renderImage(node ast.Node, entering bool) ast.WalkStatus {
@@ -372,25 +1099,126 @@ func (r *Renderer) renderImage(node ast.Node, entering bool) ast.WalkStatus {
 	if entering {
 		r.rc.writer.WriteBytes([]byte("!"))
 	}
-	return r.renderLinkCommon(n.Title, n.Destination, entering)
+	return r.renderLinkCommon(n, n.Title, n.Destination, entering)
 }
 
-func (r *Renderer) renderLinkCommon(title, destination []byte, entering bool) ast.WalkStatus {
+func (r *Renderer) renderLinkCommon(node ast.Node, title, destination []byte, entering bool) ast.WalkStatus {
+	// Reference links render their text up front, so their children are skipped
+	if ref := linkReferenceOf(node); ref != nil && r.config.LinkStyle != LinkStyleInline {
+		if entering {
+			r.renderReferenceLink(node, ref, title, destination)
+		}
+		return ast.WalkSkipChildren
+	}
+	if r.config.LinkStyle.IsReference() {
+		if entering {
+			r.renderGeneratedReferenceLink(node, title, destination)
+		}
+		return ast.WalkSkipChildren
+	}
 	if entering {
 		r.rc.writer.WriteBytes([]byte("["))
 	} else {
-		r.rc.writer.WriteBytes([]byte("]("))
-		r.rc.writer.WriteBytes(destination)
-		if len(title) > 0 {
-			r.rc.writer.WriteBytes([]byte(" \""))
-			r.rc.writer.WriteBytes(title)
-			r.rc.writer.WriteBytes([]byte("\""))
-		}
-		r.rc.writer.WriteBytes([]byte(")"))
+		r.rc.writer.WriteBytes([]byte("]"))
+		r.renderLinkDestination(title, destination)
 	}
 	return ast.WalkContinue
 }
 
+// renderLinkDestination renders the inline destination and title of a link or image.
+func (r *Renderer) renderLinkDestination(title, destination []byte) {
+	r.rc.writer.WriteBytes([]byte("("))
+	// Inline links can leave out an empty destination, unless it is followed by a title
+	if len(destination) > 0 || len(title) > 0 {
+		r.writeLinkDestination(destination)
+	}
+	if len(title) > 0 {
+		r.rc.writer.WriteBytes([]byte(" \""))
+		r.rc.writer.WriteBytes(title)
+		r.rc.writer.WriteBytes([]byte("\""))
+	}
+	r.rc.writer.WriteBytes([]byte(")"))
+}
+
+// writeLinkDestination writes the destination of a link, image or link reference definition. The
+// destination is enclosed in pointy brackets if it is empty or contains spaces or control
+// characters, which would otherwise end it.
+func (r *Renderer) writeLinkDestination(destination []byte) {
+	if len(destination) == 0 || bytes.ContainsFunc(destination, func(c rune) bool { return c <= ' ' || c == 0x7f }) {
+		r.rc.writer.WriteBytes([]byte("<"))
+		r.rc.writer.WriteBytes(destination)
+		r.rc.writer.WriteBytes([]byte(">"))
+	} else {
+		r.rc.writer.WriteBytes(destination)
+	}
+}
+
+// renderReferenceLink renders a link or image that was parsed from a reference link in the same
+// form as the source. The link is rendered inline instead if it no longer matches its definition,
+// e.g. because a transformer changed its destination.
+func (r *Renderer) renderReferenceLink(node ast.Node, ref *linkReference, title, destination []byte) {
+	text := r.renderChildrenBytes(node)
+	r.rc.writer.WriteBytes([]byte("["))
+	r.rc.writer.WriteBytes(text)
+	r.rc.writer.WriteBytes([]byte("]"))
+
+	// Collapsed and shortcut references use the link text as their label
+	label := text
+	if ref.form == linkReferenceFull {
+		label = ref.label
+	}
+	definition := r.linkReferenceDefinition(node, label)
+	if definition == nil || !bytes.Equal(definition.Destination, destination) || !bytes.Equal(definition.Title, title) {
+		r.renderLinkDestination(title, destination)
+		return
+	}
+	switch ref.form {
+	case linkReferenceFull:
+		r.rc.writer.WriteBytes([]byte("["))
+		r.rc.writer.WriteBytes(label)
+		r.rc.writer.WriteBytes([]byte("]"))
+	case linkReferenceCollapsed:
+		r.rc.writer.WriteBytes([]byte("[]"))
+	}
+}
+
+// renderGeneratedReferenceLink renders an inline link or image as a reference link, generating a
+// link reference definition for it unless one with the same destination and title already exists.
+func (r *Renderer) renderGeneratedReferenceLink(node ast.Node, title, destination []byte) {
+	text := r.renderChildrenBytes(node)
+	definition := r.generatedLinkReferenceDefinition(node, text, title, destination)
+	r.rc.writer.WriteBytes([]byte("["))
+	r.rc.writer.WriteBytes(text)
+	r.rc.writer.WriteBytes([]byte("]"))
+	switch {
+	case util.ToLinkReference(definition.Label) != util.ToLinkReference(text):
+		r.rc.writer.WriteBytes([]byte("["))
+		r.rc.writer.WriteBytes(definition.Label)
+		r.rc.writer.WriteBytes([]byte("]"))
+	case !canFollowShortcutReference(node.NextSibling(), r.rc.source):
+		r.rc.writer.WriteBytes([]byte("[]"))
+	}
+}
+
+// canFollowShortcutReference returns true if the given inline node can follow a shortcut reference
+// link without changing its meaning. A shortcut reference followed by a parenthesis or bracket would
+// be parsed as an inline link or full reference, and one followed by a colon at the start of a
+// paragraph would be parsed as a link reference definition.
+func canFollowShortcutReference(next ast.Node, source []byte) bool {
+	var value []byte
+	switch n := next.(type) {
+	case nil:
+		return true
+	case *ast.Text:
+		value = n.Segment.Value(source)
+	case *ast.String:
+		value = n.Value
+	default:
+		return next.Kind() != ast.KindLink && next.Kind() != east.KindFootnoteLink
+	}
+	return len(value) == 0 || (value[0] != '(' && value[0] != '[' && value[0] != ':')
+}
+
 func (r *Renderer) renderCodeSpan(node ast.Node, entering bool) ast.WalkStatus {
 	if entering {
 		// get contents of codespan
@@ -457,22 +1285,718 @@ func (r *Renderer) renderCodeSpan(node ast.Node, entering bool) ast.WalkStatus {
 
 func (r *Renderer) renderEmphasis(node ast.Node, entering bool) ast.WalkStatus {
 	n := node.(*ast.Emphasis)
-	r.rc.writer.WriteBytes(bytes.Repeat([]byte{'*'}, n.Level))
+	stack := &r.rc.emphasisStack
+	if entering {
+		c := r.emphasisDelimiter(n)
+		*stack = append(*stack, emphasisContext{delimiter: c, before: r.emphasisBefore(n, c)})
+	}
+	c := (*stack)[len(*stack)-1].delimiter
+	if !entering {
+		*stack = (*stack)[:len(*stack)-1]
+	}
+	r.rc.writer.WriteBytes(bytes.Repeat([]byte{c}, n.Level))
+	return ast.WalkContinue
+}
+
+// emphasisDelimiter returns the delimiter character to render the given emphasis with. Underscores
+// are only used where they can open and close the emphasis, which they can't inside words or next
+// to some punctuation.
+func (r *Renderer) emphasisDelimiter(n *ast.Emphasis) byte {
+	top := len(r.rc.emphasisStack) - 1
+	if parent, ok := n.Parent().(*ast.Emphasis); ok {
+		// An asterisk run inside a word can only delimit if it isn't followed or preceded by
+		// punctuation, so it can't be split into runs of different delimiters.
+		outer := r.rc.emphasisStack[top]
+		if outer.delimiter == '*' &&
+			(n.PreviousSibling() == nil && !isSpaceOrPunct(outer.before) ||
+				n.NextSibling() == nil && !isSpaceOrPunct(r.emphasisAfter(parent, '*', top-1))) {
+			return '*'
+		}
+	}
+	underscore, preserve := false, false
+	if n.Level == 2 {
+		underscore = r.config.StrongStyle == StrongStyleUnderscore
+		preserve = r.config.StrongStyle == StrongStylePreserve
+	} else {
+		underscore = r.config.EmphasisStyle == EmphasisStyleUnderscore
+		preserve = r.config.EmphasisStyle == EmphasisStylePreserve
+	}
+	if preserve {
+		underscore = sourceEmphasisDelimiter(n, r.rc.source) == '_'
+	}
+	if !underscore || r.hasUnderscoreText(n) {
+		return '*'
+	}
+	first := r.emphasisContentRune(n, true)
+	last := r.emphasisContentRune(n, false)
+	if canOpenUnderscore(r.emphasisBefore(n, '_'), first) &&
+		canCloseUnderscore(last, r.emphasisAfter(n, '_', top)) {
+		return '_'
+	}
+	return '*'
+}
+
+// emphasisBefore returns the rune written before the opening delimiters of the given emphasis
+// if they are written with c. Opening delimiters of parent emphasis with the same character are
+// part of the same run.
+func (r *Renderer) emphasisBefore(n *ast.Emphasis, c byte) rune {
+	if _, ok := n.Parent().(*ast.Emphasis); ok && n.PreviousSibling() == nil {
+		if outer := r.rc.emphasisStack[len(r.rc.emphasisStack)-1]; outer.delimiter == c {
+			return outer.before
+		}
+	}
+	return r.rc.writer.lastRune()
+}
+
+// emphasisAfter returns the rune written after the closing delimiters of the given emphasis if
+// they are written with c, where depth is the index of its parent in the emphasis stack. Closing
+// delimiters of parent emphasis with the same character are part of the same run.
+func (r *Renderer) emphasisAfter(n *ast.Emphasis, c byte, depth int) rune {
+	if next := n.NextSibling(); next != nil {
+		return r.edgeRune(next, true)
+	}
+	if parent, ok := n.Parent().(*ast.Emphasis); ok && r.rc.emphasisStack[depth].delimiter == c {
+		return r.emphasisAfter(parent, c, depth-1)
+	}
+	if n.Parent() != nil && n.Parent().Type() != ast.TypeBlock {
+		// The emphasis is followed by the closing markup of its parent
+		return '*'
+	}
+	return rune(lineDelim)
+}
+
+// isSpaceOrPunct returns true if c is whitespace or punctuation.
+func isSpaceOrPunct(c rune) bool {
+	return util.IsSpaceRune(c) || util.IsPunctRune(c)
+}
+
+// emphasisContentRune returns the first or last rune of the content of the given emphasis, not
+// counting the delimiters of nested emphasis.
+func (r *Renderer) emphasisContentRune(n *ast.Emphasis, first bool) rune {
+	child := n.LastChild()
+	if first {
+		child = n.FirstChild()
+	}
+	if nested, ok := child.(*ast.Emphasis); ok {
+		return r.emphasisContentRune(nested, first)
+	}
+	return r.edgeRune(child, first)
+}
+
+// hasUnderscoreText returns true if the source text inside the given emphasis contains an
+// unescaped underscore. Text from the source isn't escaped again, so such an underscore could
+// close emphasis rendered with underscores.
+func (r *Renderer) hasUnderscoreText(n *ast.Emphasis) bool {
+	found := false
+	_ = ast.Walk(n, func(node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := node.(type) {
+		case *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			value := node.Value(r.rc.source)
+			for i := 0; i < len(value) && !found; i++ {
+				if value[i] == '\\' {
+					i++
+				} else if value[i] == '_' {
+					found = true
+				}
+			}
+		}
+		if found {
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
+
+// edgeRune returns the first or last rune that the given inline node is rendered with. Inlines other
+// than text, such as links and code spans, start and end with punctuation.
+func (r *Renderer) edgeRune(node ast.Node, first bool) rune {
+	var value []byte
+	switch n := node.(type) {
+	case *ast.Text:
+		value = n.Value(r.rc.source)
+	case *ast.String:
+		value = n.Value
+	}
+	if len(value) == 0 {
+		return '*'
+	}
+	if first {
+		c, _ := utf8.DecodeRune(value)
+		return c
+	}
+	c, _ := utf8.DecodeLastRune(value)
+	return c
+}
+
+// sourceEmphasisDelimiter returns the delimiter character that the given emphasis was written with
+// in the source, or 0 if it can't be found. Emphasis nodes don't have segments, so the delimiter is
+// found before the text at the start of the emphasis, after the delimiters of any nested emphasis.
+func sourceEmphasisDelimiter(n *ast.Emphasis, source []byte) byte {
+	offset := 0
+	for node := ast.Node(n); node != nil; node = node.FirstChild() {
+		switch node := node.(type) {
+		case *ast.Emphasis:
+			offset += node.Level
+		case *ast.Text:
+			if i := node.Segment.Start - offset; i >= 0 && i < len(source) &&
+				(source[i] == '*' || source[i] == '_') {
+				return source[i]
+			}
+			return 0
+		default:
+			return 0
+		}
+	}
+	return 0
+}
+
+func (r *Renderer) renderTable(node ast.Node, entering bool) ast.WalkStatus {
+	n := node.(*east.Table)
+	if !entering {
+		return ast.WalkContinue
+	}
+	// Cells are padded to the width of their column, so all cells are rendered up front.
+	t := tableContext{
+		alignments: n.Alignments,
+		widths:     make([]int, len(n.Alignments)),
+	}
+	for i := range t.widths {
+		t.widths[i] = tableColumnWidthMinimum
+	}
+	for row := n.FirstChild(); row != nil; row = row.NextSibling() {
+		column := 0
+		for cell := row.FirstChild(); cell != nil; cell = cell.NextSibling() {
+			contents := escapeTablePipes(r.renderChildrenBytes(cell))
+			if column < len(t.widths) {
+				t.widths[column] = max(t.widths[column], displayWidth(contents))
+			}
+			t.cells = append(t.cells, contents)
+			column++
+		}
+	}
+	r.rc.tableContext = t
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderTableRow(node ast.Node, entering bool) ast.WalkStatus {
+	t := &r.rc.tableContext
+	if entering {
+		t.column = 0
+		return ast.WalkContinue
+	}
+	r.rc.writer.WriteBytes([]byte("|"))
+	r.rc.writer.FlushLine()
+	if node.Kind() == east.KindTableHeader {
+		// The delimiter row follows the header row
+		for i, width := range t.widths {
+			r.rc.writer.WriteBytes([]byte("| "))
+			r.rc.writer.WriteBytes(tableDelimiter(t.alignments[i], width))
+			r.rc.writer.WriteBytes([]byte(" "))
+		}
+		r.rc.writer.WriteLine([]byte("|"))
+	}
 	return ast.WalkContinue
 }
 
+func (r *Renderer) renderTableCell(node ast.Node, entering bool) ast.WalkStatus {
+	t := &r.rc.tableContext
+	if entering {
+		contents := t.cells[t.cell]
+		r.rc.writer.WriteBytes([]byte("| "))
+		if t.column < len(t.widths) {
+			r.rc.writer.WriteBytes(padTableCell(contents, t.alignments[t.column], t.widths[t.column]))
+		} else {
+			r.rc.writer.WriteBytes(contents)
+		}
+		r.rc.writer.WriteBytes([]byte(" "))
+		t.cell++
+		t.column++
+	}
+	// Cell contents were already rendered by renderTable
+	return ast.WalkSkipChildren
+}
+
+// tableColumnWidthMinimum is the minimum width of a table column, which is the minimum number of
+// characters required by the delimiter row.
+const tableColumnWidthMinimum = 3
+
+// tableDelimiter returns the delimiter row cell for a column with the given alignment and width.
+func tableDelimiter(alignment east.Alignment, width int) []byte {
+	delimiter := bytes.Repeat([]byte("-"), width)
+	switch alignment {
+	case east.AlignLeft:
+		delimiter[0] = ':'
+	case east.AlignRight:
+		delimiter[width-1] = ':'
+	case east.AlignCenter:
+		delimiter[0] = ':'
+		delimiter[width-1] = ':'
+	}
+	return delimiter
+}
+
+// padTableCell pads the given cell contents with spaces to the given width according to alignment.
+func padTableCell(contents []byte, alignment east.Alignment, width int) []byte {
+	padding := max(width-displayWidth(contents), 0)
+	var left int
+	switch alignment {
+	case east.AlignRight:
+		left = padding
+	case east.AlignCenter:
+		left = padding / 2
+	}
+	result := bytes.Repeat([]byte(" "), left)
+	result = append(result, contents...)
+	return append(result, bytes.Repeat([]byte(" "), padding-left)...)
+}
+
+// displayWidth returns the number of columns that text takes up in a monospace font. East Asian
+// wide and fullwidth characters, and emoji, take up two columns, and combining marks and other
+// zero width characters take up none.
+func displayWidth(text []byte) int {
+	width := 0
+	for _, c := range string(text) {
+		switch {
+		case unicode.In(c, unicode.Mn, unicode.Me, unicode.Cf, unicode.Cc):
+		case unicode.Is(wideRunes, c):
+			width += 2
+		default:
+			width++
+		}
+	}
+	return width
+}
+
+// wideRunes holds the ranges of East Asian wide and fullwidth characters, and emoji, that take up
+// two columns.
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1}, // Hangul Jamo initial consonants
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1}, // CJK radicals, symbols and punctuation
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1}, // Hiragana, Katakana and CJK compatibility
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1}, // CJK unified ideographs extension A
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1}, // CJK unified ideographs
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1}, // Yi
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1}, // Hangul Jamo extended A
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1}, // Hangul syllables
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1}, // CJK compatibility ideographs
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1}, // Vertical forms
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1}, // CJK compatibility and small forms
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1}, // Fullwidth forms
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1}, // Fullwidth signs
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1}, // Pictographs and emoticons
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1}, // Transport and map symbols
+		{Lo: 0x1f900, Hi: 0x1f9ff, Stride: 1}, // Supplemental symbols and pictographs
+		{Lo: 0x20000, Hi: 0x2fffd, Stride: 1}, // CJK unified ideographs extensions
+		{Lo: 0x30000, Hi: 0x3fffd, Stride: 1},
+	},
+}
+
+// escapeTablePipes escapes any pipe characters in the given table cell contents that are not
+// already escaped, as they would otherwise be parsed as cell delimiters.
+func escapeTablePipes(contents []byte) []byte {
+	var result []byte
+	backslashes := 0
+	for _, c := range contents {
+		if c == '|' && backslashes%2 == 0 {
+			result = append(result, '\\')
+		}
+		if c == '\\' {
+			backslashes++
+		} else {
+			backslashes = 0
+		}
+		result = append(result, c)
+	}
+	return result
+}
+
+// renderChildrenBytes renders the children of the given node into a byte slice instead of the
+// output. It is used by blocks that need to measure their contents before writing them.
+func (r *Renderer) renderChildrenBytes(node ast.Node) []byte {
+	buf := bytes.Buffer{}
+	writer := r.rc.writer
+	r.rc.writer = newMarkdownWriter(&buf, r.config)
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		_ = ast.Walk(c, r.walk)
+	}
+	r.rc.writer.FlushLine()
+	r.rc.writer = writer
+	return bytes.TrimSuffix(buf.Bytes(), []byte{lineDelim})
+}
+
+func (r *Renderer) renderStrikethrough(node ast.Node, entering bool) ast.WalkStatus {
+	r.rc.writer.WriteBytes(r.config.StrikethroughStyle.Delimiter())
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderTaskCheckBox(node ast.Node, entering bool) ast.WalkStatus {
+	n := node.(*east.TaskCheckBox)
+	if entering {
+		r.rc.writer.WriteBytes(r.config.TaskCheckBoxStyle.CheckBox(n.IsChecked))
+		r.rc.writer.WriteBytes([]byte(" "))
+	}
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderDefinitionDescription(node ast.Node, entering bool) ast.WalkStatus {
+	if entering {
+		// Prefix the current line with the description marker
+		r.rc.writer.PushPrefix([]byte(": "), 0, 0)
+		// Indent subsequent lines by the width of the marker
+		r.rc.writer.PushIndent(2, 1)
+	} else {
+		r.rc.writer.PopPrefix()
+		r.rc.writer.PopPrefix()
+	}
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderFootnoteLink(node ast.Node, entering bool) ast.WalkStatus {
+	n := node.(*east.FootnoteLink)
+	if entering {
+		r.rc.writer.WriteBytes([]byte("[^"))
+		if footnote := r.footnote(node, n.Index); footnote != nil {
+			r.rc.writer.WriteBytes(r.footnoteLabel(footnote))
+			r.citeFootnote(footnote)
+		} else {
+			r.rc.writer.WriteBytes([]byte(strconv.Itoa(n.Index)))
+		}
+		r.rc.writer.WriteBytes([]byte("]"))
+	}
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderFootnoteBacklink(node ast.Node, entering bool) ast.WalkStatus {
+	// Backlinks are added to footnote definitions for HTML output, and have no markdown equivalent.
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderFootnote(node ast.Node, entering bool) ast.WalkStatus {
+	n := node.(*east.Footnote)
+	if entering {
+		label := append([]byte("[^"), r.footnoteLabel(n)...)
+		r.rc.writer.PushPrefix(append(label, "]: "...), 0, 0)
+		r.rc.writer.PushIndent(4, 1)
+		// Footnotes without content still need their label. The footnote extension adds backlinks
+		// directly to footnotes without a paragraph.
+		if c := n.FirstChild(); c == nil || c.Kind() == east.KindFootnoteBacklink {
+			r.rc.writer.EndLine()
+		}
+	} else {
+		r.rc.writer.PopPrefix()
+		r.rc.writer.PopPrefix()
+	}
+	return ast.WalkContinue
+}
+
+func (r *Renderer) renderFootnoteList(node ast.Node, entering bool) ast.WalkStatus {
+	if entering {
+		// Footnotes that weren't rendered at the end of a section are rendered here, along with any
+		// footnotes whose references were never rendered.
+		for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+			r.citeFootnote(c.(*east.Footnote))
+		}
+		r.renderPendingFootnotes()
+	}
+	return ast.WalkSkipChildren
+}
+
+// renderSectionEnd renders the footnotes and link reference definitions of the previous section
+// before a top-level heading when they are placed at the end of sections.
+func (r *Renderer) renderSectionEnd(node ast.Node, entering bool) ast.WalkStatus {
+	if !entering || node.Parent() == nil || node.Parent().Kind() != ast.KindDocument {
+		return ast.WalkContinue
+	}
+	rendered := false
+	if r.config.FootnotePlacement == FootnotePlacementSectionEnd && len(r.rc.footnoteContext.pending) > 0 {
+		r.renderPendingFootnotes()
+		rendered = true
+	}
+	if l := &r.rc.linkReferenceContext; len(l.section) > 0 {
+		r.renderLinkReferenceDefinitions(l.section)
+		l.section = nil
+		rendered = true
+	}
+	// The heading could otherwise be a lazy continuation line of the last footnote
+	if _, blank := r.previousSibling(node); rendered && !blank {
+		r.rc.writer.EndLine()
+	}
+	return ast.WalkContinue
+}
+
+// renderPendingFootnotes renders the definitions of footnotes that have been cited but not yet
+// rendered, separated from the previous block by a blank line.
+func (r *Renderer) renderPendingFootnotes() {
+	f := &r.rc.footnoteContext
+	// Footnotes can cite other footnotes, which are appended to pending as they are rendered.
+	for i := 0; i < len(f.pending); i++ {
+		// Separate footnotes spanning multiple blocks from the next footnote for readability
+		if i == 0 || f.pending[i-1].ChildCount() > 1 {
+			r.rc.writer.EndLine()
+		}
+		_ = ast.Walk(f.pending[i], r.walk)
+	}
+	f.pending = nil
+}
+
+// footnote returns the footnote definition with the given index, or nil if there isn't one.
+func (r *Renderer) footnote(node ast.Node, index int) *east.Footnote {
+	f := &r.rc.footnoteContext
+	if f.footnotes == nil {
+		f.footnotes = map[int]*east.Footnote{}
+		// The footnote extension moves all footnote definitions to a list at the end of the document
+		root := node
+		for root.Parent() != nil {
+			root = root.Parent()
+		}
+		for list := root.FirstChild(); list != nil; list = list.NextSibling() {
+			if list.Kind() != east.KindFootnoteList {
+				continue
+			}
+			for c := list.FirstChild(); c != nil; c = c.NextSibling() {
+				footnote := c.(*east.Footnote)
+				f.footnotes[footnote.Index] = footnote
+			}
+		}
+	}
+	return f.footnotes[index]
+}
+
+// footnoteLabel returns the label to render for the given footnote.
+func (r *Renderer) footnoteLabel(footnote *east.Footnote) []byte {
+	if r.config.FootnoteLabelStyle == FootnoteLabelStyleNumbered {
+		// The footnote extension numbers footnotes in order of first reference
+		return []byte(strconv.Itoa(footnote.Index))
+	}
+	return footnote.Ref
+}
+
+// citeFootnote marks the given footnote as pending if it has not already been cited.
+func (r *Renderer) citeFootnote(footnote *east.Footnote) {
+	f := &r.rc.footnoteContext
+	if f.cited == nil {
+		f.cited = map[*east.Footnote]bool{}
+	}
+	if !f.cited[footnote] {
+		f.cited[footnote] = true
+		f.pending = append(f.pending, footnote)
+	}
+}
+
+func (r *Renderer) renderLinkReferenceDefinition(node ast.Node, entering bool) ast.WalkStatus {
+	n := node.(*LinkReferenceDefinition)
+	if r.isMovedLinkReferenceDefinition(n) {
+		if entering {
+			r.rc.linkReferenceContext.pending = append(r.rc.linkReferenceContext.pending, n)
+		}
+		return ast.WalkSkipChildren
+	}
+	r.renderBlockSeparator(node, entering)
+	if entering {
+		r.writeLinkReferenceDefinition(n)
+	}
+	return ast.WalkContinue
+}
+
+// isMovedLinkReferenceDefinition returns true if node is a link reference definition that is
+// rendered at the end of the document instead of where it was defined.
+func (r *Renderer) isMovedLinkReferenceDefinition(node ast.Node) bool {
+	return node.Kind() == KindLinkReferenceDefinition &&
+		r.config.LinkReferenceDefinitionPlacement != LinkReferenceDefinitionPlacementPreserve
+}
+
+// hasMovedLinkReferenceDefinition returns true if any of the children of node is a link reference
+// definition that is moved.
+func (r *Renderer) hasMovedLinkReferenceDefinition(node ast.Node) bool {
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		if r.isMovedLinkReferenceDefinition(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// isEmptyContainer returns true if none of the children of the given container block are rendered
+// in place. Empty containers are written as a line with just their prefix, so they aren't lost.
+func (r *Renderer) isEmptyContainer(node ast.Node) bool {
+	for c := node.FirstChild(); c != nil; c = c.NextSibling() {
+		if !r.isMovedLinkReferenceDefinition(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// renderLinkReferenceDefinitions renders the given link reference definitions after the previous
+// block, separated from it by a blank line.
+func (r *Renderer) renderLinkReferenceDefinitions(pending []*LinkReferenceDefinition) {
+	// Only the first definition of a label has any effect
+	seen := map[string]bool{}
+	definitions := []*LinkReferenceDefinition{}
+	for _, definition := range pending {
+		label := util.ToLinkReference(definition.Label)
+		if !seen[label] {
+			seen[label] = true
+			definitions = append(definitions, definition)
+		}
+	}
+	if r.config.LinkReferenceDefinitionPlacement == LinkReferenceDefinitionPlacementSorted {
+		slices.SortStableFunc(definitions, func(a, b *LinkReferenceDefinition) int {
+			return strings.Compare(util.ToLinkReference(a.Label), util.ToLinkReference(b.Label))
+		})
+	}
+	for i, definition := range definitions {
+		// The blank line before the definitions may already have been written, by the separator of
+		// a block that was left empty by moving its definitions
+		if i == 0 && r.rc.writer.line > 0 && r.rc.writer.blankLines == 0 {
+			r.rc.writer.EndLine()
+		}
+		r.writeLinkReferenceDefinition(definition)
+		r.rc.writer.FlushLine()
+	}
+}
+
+// writeLinkReferenceDefinition writes the given link reference definition on the current line.
+func (r *Renderer) writeLinkReferenceDefinition(n *LinkReferenceDefinition) {
+	r.rc.writer.WriteBytes([]byte("["))
+	r.rc.writer.WriteBytes(n.Label)
+	r.rc.writer.WriteBytes([]byte("]: "))
+	r.writeLinkDestination(n.Destination)
+	if len(n.Title) > 0 {
+		// The title is rendered as it was written in the source, so use delimiters it doesn't contain
+		opener, closer := []byte("\""), []byte("\"")
+		if bytes.ContainsRune(n.Title, '"') {
+			opener, closer = []byte("'"), []byte("'")
+			if bytes.ContainsRune(n.Title, '\'') {
+				opener, closer = []byte("("), []byte(")")
+			}
+		}
+		r.rc.writer.WriteBytes([]byte(" "))
+		r.rc.writer.WriteBytes(opener)
+		r.rc.writer.WriteBytes(n.Title)
+		r.rc.writer.WriteBytes(closer)
+	}
+}
+
+// linkReferenceDefinition returns the link reference definition that the given label refers to, or
+// nil if there isn't one.
+func (r *Renderer) linkReferenceDefinition(node ast.Node, label []byte) *LinkReferenceDefinition {
+	l := &r.rc.linkReferenceContext
+	if l.definitions == nil {
+		l.definitions = map[string]*LinkReferenceDefinition{}
+		l.targets = map[linkTarget]*LinkReferenceDefinition{}
+		root := node
+		for root.Parent() != nil {
+			root = root.Parent()
+		}
+		_ = ast.Walk(root, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if definition, ok := n.(*LinkReferenceDefinition); ok && entering {
+				// Like goldmark, the first definition of a label takes precedence
+				key := util.ToLinkReference(definition.Label)
+				if _, ok := l.definitions[key]; !ok {
+					l.definitions[key] = definition
+					target := linkTarget{string(definition.Destination), string(definition.Title)}
+					if _, ok := l.targets[target]; !ok {
+						l.targets[target] = definition
+					}
+				}
+			}
+			return ast.WalkContinue, nil
+		})
+	}
+	return l.definitions[util.ToLinkReference(label)]
+}
+
+// generatedLinkReferenceDefinition returns the link reference definition for an inline link with the
+// given text, title, and destination. Links with the same destination and title share a definition,
+// which is generated the first time it is needed.
+func (r *Renderer) generatedLinkReferenceDefinition(node ast.Node, text, title, destination []byte) *LinkReferenceDefinition {
+	l := &r.rc.linkReferenceContext
+	// Make sure the definitions from the source are known
+	_ = r.linkReferenceDefinition(node, nil)
+	target := linkTarget{string(destination), string(title)}
+	if definition, ok := l.targets[target]; ok {
+		return definition
+	}
+
+	label := text
+	if r.config.LinkLabelStyle == LinkLabelStyleNumbered || !isGeneratedLabel(text) || l.definitions[util.ToLinkReference(text)] != nil {
+		for {
+			l.number++
+			label = []byte(strconv.Itoa(l.number))
+			if l.definitions[util.ToLinkReference(label)] == nil {
+				break
+			}
+		}
+	}
+	definition := &LinkReferenceDefinition{Label: label, Destination: destination, Title: title}
+	l.definitions[util.ToLinkReference(label)] = definition
+	l.targets[target] = definition
+	if r.config.LinkStyle == LinkStyleReferenceSectionEnd {
+		l.section = append(l.section, definition)
+	} else {
+		l.pending = append(l.pending, definition)
+	}
+	return definition
+}
+
+// isGeneratedLabel returns true if the given link text can be used as the label of a generated link
+// reference definition.
+func isGeneratedLabel(text []byte) bool {
+	// Labels can't contain unescaped brackets, and labels starting with a caret look like footnotes
+	return !util.IsBlank(text) && len(text) <= 999 && text[0] != '^' && !bytes.ContainsAny(text, "[]\n")
+}
+
+// linkReferenceOf returns how the given link or image referred to its link reference definition in
+// the source, or nil if it didn't.
+func linkReferenceOf(node ast.Node) *linkReference {
+	value, ok := node.AttributeString(linkReferenceAttribute)
+	if !ok {
+		return nil
+	}
+	ref, _ := value.(*linkReference)
+	return ref
+}
+
 type renderContext struct {
 	writer *markdownWriter
+	// err is the error that stopped rendering, and stopped is true if rendering was stopped by a
+	// node renderer func without an error
+	err     error
+	stopped bool
+	// document is the writer that writer writes the lines of the document to
+	document *documentWriter
 	// source is the markdown source
 	source []byte
 	// listMarkers is the marker character used for the current list
 	lists           []listContext
 	codeSpanContext codeSpanContext
+	tableContext    tableContext
+	footnoteContext footnoteContext
+	// linkReferenceContext holds the link reference definitions in the document
+	linkReferenceContext linkReferenceContext
+	// proseContext holds the text of the paragraph being wrapped
+	proseContext proseContext
+	// emphasisStack holds the emphasis being rendered, innermost last
+	emphasisStack []emphasisContext
 }
 
 type listContext struct {
 	list *ast.List
 	num  int
+	// marker is the marker character of the list items
+	marker byte
+	// tight is true if the list is rendered without blank lines between its items and their blocks
+	tight bool
 }
 
 // codeSpanContext holds state about how the current codespan should be rendererd.
@@ -483,10 +2007,73 @@ type codeSpanContext struct {
 	padSpace bool
 }
 
+// tableContext holds the pre-rendered contents of the current table.
+type tableContext struct {
+	// alignments and widths hold the alignment and width of each column
+	alignments []east.Alignment
+	widths     []int
+	// cells holds the rendered contents of every cell in the table, in document order
+	cells [][]byte
+	// cell is the index of the next cell to render
+	cell int
+	// column is the column of the next cell to render within the current row
+	column int
+}
+
+// footnoteContext holds state about the footnotes cited in the document.
+type footnoteContext struct {
+	// footnotes maps footnote indexes to their definitions
+	footnotes map[int]*east.Footnote
+	// cited holds the footnotes that have been cited so far
+	cited map[*east.Footnote]bool
+	// pending holds the cited footnotes whose definitions have not been rendered yet
+	pending []*east.Footnote
+}
+
+// linkReferenceContext holds state about the link reference definitions in the document.
+type linkReferenceContext struct {
+	// definitions maps normalized labels to the definitions they refer to
+	definitions map[string]*LinkReferenceDefinition
+	// targets maps link destinations and titles to the definitions that refer to them
+	targets map[linkTarget]*LinkReferenceDefinition
+	// pending holds the definitions to render at the end of the document
+	pending []*LinkReferenceDefinition
+	// section holds the generated definitions to render at the end of the current section
+	section []*LinkReferenceDefinition
+	// number is the last number used for a generated label
+	number int
+}
+
+// emphasisContext holds state about emphasis being rendered.
+type emphasisContext struct {
+	// delimiter is the delimiter character of the emphasis
+	delimiter byte
+	// before is the rune written before the opening delimiters
+	before rune
+}
+
+// linkTarget is the destination and title of a link.
+type linkTarget struct {
+	destination, title string
+}
+
+// proseContext holds state about the paragraph whose text is being rendered for wrapping.
+type proseContext struct {
+	// writer is the writer that the text is rendered with, and buf holds the lines it has written
+	writer *markdownWriter
+	buf    *bytes.Buffer
+	// breaks holds the offsets of the spaces in the text where lines can be broken
+	breaks []int
+}
+
 // newRenderContext returns a new renderContext object
 func newRenderContext(writer io.Writer, source []byte, config *Config) renderContext {
-	return renderContext{
-		writer: newMarkdownWriter(writer, config),
-		source: source,
+	document := newDocumentWriter(writer, source, config)
+	result := renderContext{
+		writer:   newMarkdownWriter(document, config),
+		document: document,
+		source:   source,
 	}
+	result.writer.lineEnding = lineEnding(config.LineEnding, source)
+	return result
 }
@@ -2,7 +2,9 @@ package markdown
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/rhysd/go-fakeio"
@@ -10,7 +12,9 @@ import (
 	"github.com/yuin/goldmark"
 	"github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/text"
 	"github.com/yuin/goldmark/util"
 )
@@ -78,9 +82,210 @@ func TestCustomRenderers(t *testing.T) {
 	`
 
 	extension.TaskList.Extend(md)
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&testCheckBoxRenderer{}, 0)))
 	err := md.Convert([]byte(source), &buf)
 	assert.NoError(t, err)
-	t.Log(buf.String())
+	assert.Equal(t, "# My Tasks\n- :white_check_mark: Add support for custom renderers\n", buf.String())
+}
+
+// testCheckBoxRenderer is a renderer.NodeRenderer that renders task check boxes as emoji.
+type testCheckBoxRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs
+func (c *testCheckBoxRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(east.KindTaskCheckBox, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.(*east.TaskCheckBox).IsChecked {
+			_, _ = w.WriteString(":white_check_mark: ")
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// TestCustomRendererError tests that errors returned by custom renderers stop the rendering, and
+// are returned by Render with the kind and line of the node.
+func TestCustomRendererError(t *testing.T) {
+	errFailed := fmt.Errorf("failed")
+	failing := func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		return ast.WalkStop, errFailed
+	}
+	testCases := []struct {
+		name     string
+		kind     ast.NodeKind
+		options  []Option
+		expected string
+	}{
+		{"Block", ast.KindHeading, nil, "line 3: rendering Heading node: failed"},
+		{"Inline", ast.KindEmphasis, nil, "line 6: rendering Emphasis node: failed"},
+		{"Inline in wrapped prose", ast.KindEmphasis, []Option{WithProseWrap(ProseWrapAlways)}, "line 6: rendering Emphasis node: failed"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := NewRenderer(tc.options...)
+			r.Register(tc.kind, failing)
+			md := goldmark.New(goldmark.WithRenderer(r))
+			err := md.Convert([]byte("foo\n\n# bar\n\nbaz\n*qux*"), &bytes.Buffer{})
+
+			var renderErr *NodeRenderError
+			if assert.True(t, errors.As(err, &renderErr)) {
+				assert.Equal(t, tc.kind, renderErr.Kind)
+				assert.ErrorIs(t, err, errFailed)
+				assert.Equal(t, tc.expected, err.Error())
+			}
+		})
+	}
+}
+
+// TestCustomRendererStop tests that custom renderers can stop the rendering, leaving the output
+// rendered so far.
+func TestCustomRendererStop(t *testing.T) {
+	r := NewRenderer()
+	r.Register(ast.KindHeading, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		_, _ = w.WriteString("stop")
+		return ast.WalkStop, nil
+	})
+	md := goldmark.New(goldmark.WithRenderer(r))
+	buf := bytes.Buffer{}
+	err := md.Convert([]byte("foo\n\n# bar\n\nbaz"), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\nstop\n", buf.String())
+}
+
+// TestCustomContainerRenderer tests that custom renderers can render container blocks by type
+// asserting their writer to a Writer.
+func TestCustomContainerRenderer(t *testing.T) {
+	r := NewRenderer()
+	r.Register(ast.KindBlockquote, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+		writer := w.(Writer)
+		if !entering {
+			writer.PopPrefix()
+			return ast.WalkContinue, nil
+		}
+		if n.PreviousSibling() != nil {
+			writer.EndLine()
+		}
+		_, _ = writer.WriteString(fmt.Sprintf("!!! note \"%d:%d\"", writer.Line(), writer.Column()))
+		writer.EndLine()
+		writer.PushIndent(4)
+		return ast.WalkContinue, nil
+	})
+	md := goldmark.New(goldmark.WithRenderer(r))
+	buf := bytes.Buffer{}
+	err := md.Convert([]byte("foo\n\n- > bar\n  > - baz\n\nqux"), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n\n- !!! note \"2:2\"\n      bar\n      - baz\n\nqux\n", buf.String())
+}
+
+// TestCustomRendererWriteMethods tests that text written by custom renderers with any write method
+// is prefixed by the container blocks around it.
+func TestCustomRendererWriteMethods(t *testing.T) {
+	testCases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Blockquote", "> ***", "> foo\n>\n> bar\n"},
+		{"List item", "- ***", "- foo\n\n  bar\n"},
+		{"Nested", "> 1. > ***", "> 1. > foo\n>    >\n>    > bar\n"},
+	}
+	for _, tc := range testCases {
+		for _, method := range writeMethods {
+			t.Run(tc.name+"/"+method.name, func(t *testing.T) {
+				r := NewRenderer()
+				r.Register(ast.KindThematicBreak, func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+					if !entering {
+						return ast.WalkContinue, nil
+					}
+					return ast.WalkContinue, method.write(w, "foo\n\nbar\n")
+				})
+				md := goldmark.New(goldmark.WithRenderer(r))
+				buf := bytes.Buffer{}
+				err := md.Convert([]byte(tc.source), &buf)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expected, buf.String())
+			})
+		}
+	}
+}
+
+// TestRegisterWrapper tests that wrappers add behavior around the built-in node renderers, keeping
+// the blank lines around blocks.
+func TestRegisterWrapper(t *testing.T) {
+	md := goldmark.New(goldmark.WithRenderer(NewRenderer()))
+	md.Renderer().AddOptions(renderer.WithNodeRenderers(util.Prioritized(&testWrapperRenderer{}, 0)))
+	r := md.Renderer().(*Renderer)
+	// Later wrappers wrap earlier ones, so this one sees the destination rewritten by the earlier one
+	// once it has called it
+	r.RegisterWrapper(ast.KindLink, func(next renderer.NodeRendererFunc) renderer.NodeRendererFunc {
+		return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+			status, err := next(w, source, n, entering)
+			if link := n.(*ast.Link); entering {
+				link.Destination = append([]byte("https://example.com"), link.Destination...)
+			}
+			return status, err
+		}
+	})
+
+	buf := bytes.Buffer{}
+	err := md.Convert([]byte("foo\n# Bar\nbaz [qux](/quux)"), &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo\n# Bar {#bar}\nbaz [qux](https://example.com/docs/quux)\n", buf.String())
+}
+
+// TestRegisterWrapperAfterRender tests that wrappers registered after the first render wrap the node
+// renderer funcs from the next render on.
+func TestRegisterWrapperAfterRender(t *testing.T) {
+	r := NewRenderer()
+	md := goldmark.New(goldmark.WithRenderer(r))
+	source := []byte("# foo\n*bar*")
+	buf := bytes.Buffer{}
+	assert.NoError(t, md.Convert(source, &buf))
+	assert.Equal(t, "# foo\n*bar*\n", buf.String())
+
+	suffix := func(text string) NodeRendererWrapper {
+		return func(next renderer.NodeRendererFunc) renderer.NodeRendererFunc {
+			return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+				status, err := next(w, source, n, entering)
+				if !entering {
+					_, _ = w.WriteString(text)
+				}
+				return status, err
+			}
+		}
+	}
+	r.RegisterWrapper(ast.KindEmphasis, suffix("!"))
+	r.RegisterWrapper(ast.KindEmphasis, suffix("?"))
+	// Kinds without a node renderer func extend the node renderer funcs
+	r.RegisterWrapper(ast.NewNodeKind("Unrendered"), suffix(""))
+	buf.Reset()
+	assert.NoError(t, md.Convert(source, &buf))
+	assert.Equal(t, "# foo\n*bar*!?\n", buf.String())
+}
+
+// testWrapperRenderer is a renderer.NodeRenderer that adds anchors to headings and rewrites link
+// destinations by wrapping the built-in node renderers.
+type testWrapperRenderer struct{}
+
+// RegisterFuncs implements renderer.NodeRenderer.RegisterFuncs
+func (c *testWrapperRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	wrappers := reg.(NodeRendererWrapperRegisterer)
+	wrappers.RegisterWrapper(ast.KindHeading, func(next renderer.NodeRendererFunc) renderer.NodeRendererFunc {
+		return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+			// The heading's line is ended by the built-in renderer when exiting
+			if !entering {
+				_, _ = w.WriteString(" {#" + strings.ToLower(string(n.Text(source))) + "}")
+			}
+			return next(w, source, n, entering)
+		}
+	})
+	wrappers.RegisterWrapper(ast.KindLink, func(next renderer.NodeRendererFunc) renderer.NodeRendererFunc {
+		return func(w util.BufWriter, source []byte, n ast.Node, entering bool) (ast.WalkStatus, error) {
+			link := n.(*ast.Link)
+			if entering {
+				link.Destination = append([]byte("/docs"), link.Destination...)
+			}
+			return next(w, source, n, entering)
+		}
+	})
 }
 
 // TestRenderedOutput tests that the renderer produces the expected output for all test cases
@@ -175,6 +380,12 @@ func TestRenderedOutput(t *testing.T) {
 			"> one\n> > two\n> > > three\n\n> one again",
 			"> one\n> > two\n> > > three\n\n> one again\n",
 		},
+		{
+			"Empty containers",
+			nil,
+			"foo\n\n>\n\n- a\n-\n- > - ",
+			"foo\n\n>\n\n- a\n-\n- > -\n",
+		},
 		// Code Block
 		{
 			"Space indented code block",
@@ -352,6 +563,45 @@ func TestRenderedOutput(t *testing.T) {
 			"*in emph **strong***",
 			"*in emph **strong***\n",
 		},
+		{
+			"Underscore emphasis",
+			[]goldmark.Option{goldmark.WithRendererOptions(
+				WithEmphasisStyle(EmphasisStyleUnderscore), WithStrongStyle(StrongStyleUnderscore))},
+			"*emph* **strong** ***both***",
+			"_emph_ __strong__ ___both___\n",
+		},
+		{
+			"Underscore emphasis inside words",
+			[]goldmark.Option{goldmark.WithRendererOptions(
+				WithEmphasisStyle(EmphasisStyleUnderscore), WithStrongStyle(StrongStyleUnderscore))},
+			"intra*word* **x**y foo***bar***baz",
+			"intra*word* **x**y foo***bar***baz\n",
+		},
+		{
+			"Underscore emphasis next to punctuation",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithEmphasisStyle(EmphasisStyleUnderscore))},
+			"*\"quoted\"*text, *foo*.",
+			"*\"quoted\"*text, _foo_.\n",
+		},
+		{
+			"Underscore emphasis containing underscores",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithEmphasisStyle(EmphasisStyleUnderscore))},
+			"*foo _bar* baz_",
+			"*foo _bar* baz_\n",
+		},
+		{
+			"Mixed emphasis styles",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithEmphasisStyle(EmphasisStyleUnderscore))},
+			"***both*** *emph **strong***",
+			"_**both**_ _emph **strong**_\n",
+		},
+		{
+			"Preserved emphasis",
+			[]goldmark.Option{goldmark.WithRendererOptions(
+				WithEmphasisStyle(EmphasisStylePreserve), WithStrongStyle(StrongStylePreserve))},
+			"_emph_ *emph* __strong__ **strong** *_both_*",
+			"_emph_ *emph* __strong__ **strong** *_both_*\n",
+		},
 		// Paragraph
 		{
 			"Simple paragraph",
@@ -416,6 +666,55 @@ func TestRenderedOutput(t *testing.T) {
 			"```\n!@#$%^&*\\[],./;'()\n```",
 			"```\n!@#$%^&*\\[],./;'()\n```\n",
 		},
+		{
+			"Fenced Code Block containing fences",
+			nil,
+			"~~~~md\n```go\n  ````\n    `````\n~~~~",
+			"`````md\n```go\n  ````\n    `````\n`````\n",
+		},
+		{
+			"Fenced Code Block with backticks in info",
+			nil,
+			"~~~ a`b\nfoo\n~~~",
+			"~~~a`b\nfoo\n~~~\n",
+		},
+		{
+			"Tilde fenced Code Block",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithCodeFenceStyle(CodeFenceStyleTilde))},
+			"```go\n~~~\n```",
+			"~~~~go\n~~~\n~~~~\n",
+		},
+		{
+			"Indented code block as fenced",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithCodeBlockStyle(CodeBlockStyleFenced))},
+			"    foo\n    ```\n\n```go\nbar\n```",
+			"````\nfoo\n```\n````\n\n```go\nbar\n```\n",
+		},
+		{
+			"Indented code block as fenced with info",
+			[]goldmark.Option{goldmark.WithRendererOptions(
+				WithCodeBlockStyle(CodeBlockStyleFenced), WithCodeBlockInfo("text"))},
+			"    foo",
+			"```text\nfoo\n```\n",
+		},
+		{
+			"Fenced code block as indented",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithCodeBlockStyle(CodeBlockStyleIndented))},
+			"```\nfoo\n```\n\n```go\nbar\n```",
+			"    foo\n\n```go\nbar\n```\n",
+		},
+		{
+			"Fenced code blocks that can't be indented",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithCodeBlockStyle(CodeBlockStyleIndented))},
+			"para\n```\nfoo\n```\n\n```\nbar\n```\n\n```\n\nbaz\n```\n\n- item\n\n```\nqux\n```",
+			"para\n```\nfoo\n```\n\n    bar\n\n```\n\nbaz\n```\n\n- item\n\n```\nqux\n```\n",
+		},
+		{
+			"Code blocks with trailing whitespace",
+			nil,
+			"```\nfoo  \n  \n```\n\n    bar\t\n\n> ```\n> baz \n>\n> ```",
+			"```\nfoo  \n  \n```\n\n    bar\t\n\n> ```\n> baz \n>\n> ```\n",
+		},
 		// Raw HTML
 		{
 			"Raw HTML open tags",
@@ -436,6 +735,12 @@ func TestRenderedOutput(t *testing.T) {
 			"<a foo=\"bar\" bam = 'baz <em>\"</em>'\n_boolean zoop:33=zoop:33 />\n",
 		},
 		// HTML blocks
+		{
+			"HTML block with trailing whitespace",
+			nil,
+			"<pre>\nfoo  \n</pre>  ",
+			"<pre>\nfoo  \n</pre>  \n",
+		},
 		{
 			"HTML Block Type 1",
 			nil,
@@ -503,6 +808,60 @@ func TestRenderedOutput(t *testing.T) {
 			"1. A1\n2. B1\n   - C2\n     1. D3\n     2. E3\n   - F2\n   - G2\n3. H1\n",
 			"1. A1\n2. B1\n      - C2\n          1. D3\n          2. E3\n      - F2\n      - G2\n3. H1\n",
 		},
+		{
+			"Tab indented list",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithIndentStyle(IndentStyleTabs))},
+			"1. A1\n2. B1\n   - C2\n     1. D3\n\n            code\n3. H1\n",
+			"1. A1\n2. B1\n   - C2\n   \t 1. D3\n\n   \t \t\tcode\n3. H1\n",
+		},
+		{
+			"Bullet list marker",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithBulletListMarker(BulletListMarkerAsterisk))},
+			"- A1\n- B1\n  + C2\n",
+			"* A1\n* B1\n  * C2\n",
+		},
+		{
+			"Alternating bullet list markers",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithBulletListMarker(BulletListMarkerAlternate))},
+			"* A1\n  * B2\n    1. C3\n       * D4\n* E1\n",
+			"- A1\n  * B2\n    1. C3\n       - D4\n- E1\n",
+		},
+		{
+			"Adjacent bullet lists",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithBulletListMarker(BulletListMarkerDash))},
+			"- foo\n+ bar\n\n* baz\n\n1. qux\n\n- quux",
+			"- foo\n* bar\n\n- baz\n\n1. qux\n\n- quux\n",
+		},
+		{
+			"Ordered list gaining digits",
+			nil,
+			"9. A1\n9. B1\n   - C2\n\n   D1",
+			"9. A1\n10. B1\n    - C2\n\n    D1\n",
+		},
+		{
+			"All ones ordered list numbering",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithOrderedListNumbering(OrderedListNumberingAllOnes))},
+			"1. A1\n2. B1\n\n---\n\n0. C1\n1. D1",
+			"1. A1\n1. B1\n\n---\n\n0. C1\n0. D1\n",
+		},
+		{
+			"Preserved ordered list numbering",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithOrderedListNumbering(OrderedListNumberingPreserve))},
+			"3. A1\n7.  B1\n7. C1\n1.\n   D1",
+			"3. A1\n7. B1\n7. C1\n8. D1\n",
+		},
+		{
+			"Ordered list numbering starting at one",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithOrderedListNumbering(OrderedListNumberingStartAtOne))},
+			"3. A1\n4. B1",
+			"1. A1\n2. B1\n",
+		},
+		{
+			"Ordered list delimiter",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithOrderedListDelimiter(OrderedListDelimiterParenthesis))},
+			"1. A1\n2. B1\n\n1) C1",
+			"1) A1\n2) B1\n\n1. C1\n",
+		},
 		// Block separators
 		{
 			"ATX heading block separator",
@@ -622,6 +981,323 @@ func TestRenderedOutput(t *testing.T) {
 - List item 2
 `,
 		},
+		{
+			"Spaces hard line break",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithHardLineBreakStyle(HardLineBreakStyleSpaces))},
+			"> foo\\\n> *bar*   \n> baz",
+			"> foo  \n> *bar*  \n> baz\n",
+		},
+		{
+			"Preserved hard line break",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithHardLineBreakStyle(HardLineBreakStylePreserve))},
+			"foo\\\nbar   \nbaz",
+			"foo\\\nbar  \nbaz\n",
+		},
+		{
+			"HTML hard line break",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithHardLineBreakStyle(HardLineBreakStyleHTML))},
+			"foo\\\nbar",
+			"foo<br>\nbar\n",
+		},
+		{
+			"Wrapped spaces hard line break",
+			[]goldmark.Option{goldmark.WithRendererOptions(
+				WithHardLineBreakStyle(HardLineBreakStyleSpaces), WithProseWrap(ProseWrapNever))},
+			"foo\nbar\\\nbaz",
+			"foo bar  \nbaz\n",
+		},
+		// Block spacing
+		{
+			"Blank lines in blockquote",
+			nil,
+			"> a\n>\n> # h\n>\n>     code",
+			"> a\n>\n> # h\n>\n>     code\n",
+		},
+		{
+			"Thematic break after paragraph",
+			nil,
+			"foo\n***",
+			"foo\n\n---\n",
+		},
+		{
+			"Setext heading after paragraph",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithHeadingStyle(HeadingStyleSetext))},
+			"foo\n# bar",
+			"foo\n\nbar\n===\n",
+		},
+		{
+			"Max blank lines",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithMaxBlankLines(2))},
+			"a\n\n\n\nb\n> c\n>\n>\n>\n> d",
+			"a\n\n\nb\n> c\n>\n>\n> d\n",
+		},
+		{
+			"Separated blocks",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithBlockSpacing(BlockSpacingSeparated))},
+			"foo\n# h\n```\nx\n```\n- a\n  ```\n  y\n  ```\n- b",
+			"foo\n\n# h\n\n```\nx\n```\n\n- a\n  ```\n  y\n  ```\n- b\n",
+		},
+		{
+			"Consistent list spacing",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithListSpacing(ListSpacingConsistent))},
+			"- a\n- b\n\n- c",
+			"- a\n\n- b\n\n- c\n",
+		},
+		{
+			"Tight list spacing",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithListSpacing(ListSpacingTight))},
+			"- a\n\n- b\n\n  ```\n  x\n  ```",
+			"- a\n- b\n  ```\n  x\n  ```\n",
+		},
+		{
+			"Tight list spacing with paragraphs",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithListSpacing(ListSpacingTight))},
+			"- a\n- b\n\n  c",
+			"- a\n\n- b\n\n  c\n",
+		},
+		{
+			"Loose list spacing",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithListSpacing(ListSpacingLoose))},
+			"- a\n- b\n  - c",
+			"- a\n\n- b\n\n  - c\n",
+		},
+		// Line endings
+		{
+			"CRLF line endings",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithLineEnding(LineEndingCRLF))},
+			"# foo\n\n```\ncode  \n\n```\n\n> bar\n> baz",
+			"# foo\r\n\r\n```\r\ncode  \r\n\r\n```\r\n\r\n> bar\r\n> baz\r\n",
+		},
+		{
+			"Detected line endings",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithLineEnding(LineEndingDetect))},
+			"foo  \r\nbar\r\n\r\n    code  \r\n",
+			"foo\\\r\nbar\r\n\r\n    code  \r\n",
+		},
+		{
+			"Converted line endings",
+			nil,
+			"foo\r\nbar\r\n\r\n<div>\r\n</div>\r\n",
+			"foo\nbar\n\n<div>\n</div>\n",
+		},
+		{
+			"Final newline",
+			nil,
+			"foo\n\n    code\n\n\n",
+			"foo\n\n    code\n",
+		},
+		{
+			"Preserved final newline",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithFinalNewline(FinalNewlinePreserve))},
+			"foo\n\nbar",
+			"foo\n\nbar",
+		},
+		{
+			"Byte order mark",
+			nil,
+			"\uFEFF# foo\n",
+			"\uFEFF\\# foo\n",
+		},
+		{
+			"Stripped byte order mark",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithByteOrderMark(ByteOrderMarkStrip))},
+			"\uFEFFfoo\n",
+			"foo\n",
+		},
+		// Tables
+		{
+			"Table",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			"| a | b |\n|---|---|\n| c | d |",
+			"| a   | b   |\n| --- | --- |\n| c   | d   |\n",
+		},
+		{
+			"Table alignments",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			"| none | left | center | right |\n|-|:-|:-:|-:|\n| a | b | c | d |",
+			"| none | left | center | right |\n| ---- | :--- | :----: | ----: |\n| a    | b    |   c    |     d |\n",
+		},
+		{
+			"Table padded cells",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			"a|b\n-|-\n*longer cell*|`c`\nd",
+			"| a             | b   |\n| ------------- | --- |\n| *longer cell* | `c` |\n| d             |     |\n",
+		},
+		{
+			"Table wide characters",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			"| a | b |\n|---|--:|\n| 日本語 | 😀 |\n| é | e\u0301 |",
+			"| a      |   b |\n| ------ | --: |\n| 日本語 |  😀 |\n| é      |   e\u0301 |\n",
+		},
+		{
+			"Table escaped pipes",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			"| a \\| b | `c \\| d` |\n|---|---|",
+			"| a \\| b | `c \\| d` |\n| ------ | -------- |\n",
+		},
+		{
+			"Table block separator",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Table)},
+			"Paragraph\n\n| a |\n|---|\n\n> | b |\n> |---|\n> | c |",
+			"Paragraph\n\n| a   |\n| --- |\n\n> | b   |\n> | --- |\n> | c   |\n",
+		},
+		// Strikethrough
+		{
+			"Strikethrough",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Strikethrough)},
+			"~~foo~~ ~bar~",
+			"~~foo~~ ~~bar~~\n",
+		},
+		{
+			"Single tilde strikethrough",
+			[]goldmark.Option{
+				goldmark.WithExtensions(extension.Strikethrough),
+				goldmark.WithRendererOptions(WithStrikethroughStyle(StrikethroughStyleSingleTilde)),
+			},
+			"~~foo~~ ~bar~",
+			"~foo~ ~bar~\n",
+		},
+		// Task lists
+		{
+			"Task list",
+			[]goldmark.Option{goldmark.WithExtensions(extension.TaskList)},
+			"- [x] done\n- [X] DONE\n- [ ]   todo\n  - [ ] nested",
+			"- [x] done\n- [x] DONE\n- [ ] todo\n  - [ ] nested\n",
+		},
+		{
+			"Uppercase task list",
+			[]goldmark.Option{
+				goldmark.WithExtensions(extension.TaskList),
+				goldmark.WithRendererOptions(WithTaskCheckBoxStyle(TaskCheckBoxStyleUppercase)),
+			},
+			"- [x] done\n- [ ] todo",
+			"- [X] done\n- [ ] todo\n",
+		},
+		// Definition lists
+		{
+			"Definition list",
+			[]goldmark.Option{goldmark.WithExtensions(extension.DefinitionList)},
+			"Apple\nPomme\n:   Fruit\n:   Company\n\nOrange\n: Fruit",
+			"Apple\nPomme\n: Fruit\n: Company\n\nOrange\n: Fruit\n",
+		},
+		{
+			"Multi-paragraph definition",
+			[]goldmark.Option{goldmark.WithExtensions(extension.DefinitionList)},
+			"Term\n\n:   Para 1\n    continued\n\n    Para 2\n\n        code\n\n    - item",
+			"Term\n\n: Para 1\n  continued\n\n  Para 2\n\n      code\n\n  - item\n",
+		},
+		{
+			"Definition list block separator",
+			[]goldmark.Option{goldmark.WithExtensions(extension.DefinitionList)},
+			"Paragraph\n\nTerm\n: Def\n\n> Term\n> : Def",
+			"Paragraph\n\nTerm\n: Def\n\n> Term\n> : Def\n",
+		},
+		// Footnotes
+		{
+			"Footnotes",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Footnote)},
+			"Foo[^note] bar[^1] baz[^note].\n\n[^1]: One\n[^note]: Note\n[^unused]: Unused",
+			"Foo[^note] bar[^1] baz[^note].\n\n[^note]: Note\n[^1]: One\n",
+		},
+		{
+			"Multi-paragraph footnote",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Footnote)},
+			"Foo[^1]\n\n[^1]: Para 1\n  continued\n\n    Para 2\n\n    > quote\n\n[^2]:",
+			"Foo[^1]\n\n[^1]: Para 1\n    continued\n\n    Para 2\n\n    > quote\n",
+		},
+		{
+			"Empty footnote",
+			[]goldmark.Option{goldmark.WithExtensions(extension.Footnote)},
+			"Foo[^1]\n\n[^1]:",
+			"Foo[^1]\n\n[^1]:\n",
+		},
+		{
+			"Numbered footnotes",
+			[]goldmark.Option{
+				goldmark.WithExtensions(extension.Footnote),
+				goldmark.WithRendererOptions(WithFootnoteLabelStyle(FootnoteLabelStyleNumbered)),
+			},
+			"Foo[^b] bar[^a].\n\n[^a]: A[^c]\n[^b]: B\n[^c]: C",
+			"Foo[^1] bar[^2].\n\n[^1]: B\n[^2]: A[^3]\n[^3]: C\n",
+		},
+		{
+			"Footnotes at section end",
+			[]goldmark.Option{
+				goldmark.WithExtensions(extension.Footnote),
+				goldmark.WithRendererOptions(WithFootnotePlacement(FootnotePlacementSectionEnd)),
+			},
+			"# One\nFoo[^a]\n## Sub\nBar[^b]\n# Two\nBaz[^a][^c]\n\n[^a]: A\n[^b]: B\n[^c]: C",
+			"# One\nFoo[^a]\n\n[^a]: A\n\n## Sub\nBar[^b]\n\n[^b]: B\n\n# Two\nBaz[^a][^c]\n\n[^c]: C\n",
+		},
+		// Reference links
+		{
+			"Reference links",
+			[]goldmark.Option{goldmark.WithExtensions(NewExtension())},
+			"[Full][foo] [collapsed][] ![shortcut]\n\n[foo]:  /url  \"title\"\n[COLLAPSED]: </a b>\n[shortcut]: /img 'a \"b\"'\n> [quote]: /q\n",
+			"[Full][foo] [collapsed][] ![shortcut]\n\n[foo]: /url \"title\"\n[COLLAPSED]: </a b>\n[shortcut]: /img 'a \"b\"'\n> [quote]: /q\n",
+		},
+		{
+			"Reference link definitions at document end",
+			[]goldmark.Option{goldmark.WithExtensions(NewExtension(
+				WithLinkReferenceDefinitionPlacement(LinkReferenceDefinitionPlacementDocumentEnd),
+			))},
+			"[b]: /b\n[a]: /a\n# [a] [b]\n[a]: /duplicate\n- [c]: /c\n\n  [c]",
+			"# [a] [b]\n\n- [c]\n\n[b]: /b\n[a]: /a\n[c]: /c\n",
+		},
+		{
+			"Sorted reference link definitions",
+			[]goldmark.Option{goldmark.WithExtensions(NewExtension(
+				WithLinkReferenceDefinitionPlacement(LinkReferenceDefinitionPlacementSorted),
+			))},
+			"[b]: /b\n[a]: /a\n\n[a] [b]\n\n[C]: /c",
+			"[a] [b]\n\n[a]: /a\n[b]: /b\n[C]: /c\n",
+		},
+		{
+			"Inline link style",
+			[]goldmark.Option{goldmark.WithExtensions(NewExtension(WithLinkStyle(LinkStyleInline)))},
+			"[foo] [bar][foo] [baz](/baz)\n\n[foo]: /url",
+			"[foo](/url) [bar](/url) [baz](/baz)\n\n[foo]: /url\n",
+		},
+		{
+			"Inline link style with spaces in destination",
+			[]goldmark.Option{goldmark.WithExtensions(NewExtension(WithLinkStyle(LinkStyleInline)))},
+			"[Foo bar]:\n<my url>\n'title'\n\n[Foo bar] [baz]\n\n[baz]: <>",
+			"[Foo bar]: <my url> \"title\"\n\n[Foo bar](<my url> \"title\") [baz]()\n\n[baz]: <>\n",
+		},
+		{
+			"Reference link style",
+			[]goldmark.Option{goldmark.WithExtensions(NewExtension(WithLinkStyle(LinkStyleReferenceDocumentEnd)))},
+			"[foo](/foo) [bar](/foo) ![baz](/baz \"title\") [qux](/qux)(text) [[x]](/x) [existing](/e)\n\n[e]: /e\n",
+			"[foo] [bar][foo] ![baz] [qux][](text) [[x]][1] [existing][e]\n\n[e]: /e\n\n[foo]: /foo\n[baz]: /baz \"title\"\n[qux]: /qux\n[1]: /x\n",
+		},
+		{
+			"Numbered reference link style at section end",
+			[]goldmark.Option{goldmark.WithRenderer(NewRenderer(
+				WithLinkStyle(LinkStyleReferenceSectionEnd),
+				WithLinkLabelStyle(LinkLabelStyleNumbered),
+			))},
+			"# One\n[foo](/foo)\n# Two\n[bar](/bar) [foo](/foo)",
+			"# One\n[foo][1]\n\n[1]: /foo\n\n# Two\n[bar][2] [foo][1]\n\n[2]: /bar\n",
+		},
+		// Prose wrap
+		{
+			"Prose wrap always",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithProseWrap(ProseWrapAlways), WithLineWidth(20))},
+			"foo bar baz qux quux corge\ngrault `code span text` [link](/url \"a title\")\n\n> - foo bar baz qux quux - corge",
+			"foo bar baz qux quux\ncorge grault\n`code span text`\n[link](/url \"a title\")\n\n> - foo bar baz qux\n>   quux - corge\n",
+		},
+		{
+			"Prose wrap never",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithProseWrap(ProseWrapNever))},
+			"foo\n\\# bar\\\nbaz\n\n- qux\n  quux",
+			"foo \\# bar\\\nbaz\n\n- qux quux\n",
+		},
+		{
+			"Prose wrap sentence",
+			[]goldmark.Option{goldmark.WithRendererOptions(WithProseWrap(ProseWrapSentence))},
+			"One sentence. Another\nsentence, e.g. this one. And `code. Span` here.\n\n> - Quoted. List",
+			"One sentence.\nAnother sentence, e.g. this one.\nAnd `code. Span` here.\n\n> - Quoted.\n>   List\n",
+		},
 		{
 			"Blockquote paragraph",
 			nil,
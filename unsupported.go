@@ -0,0 +1,100 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// An ErrUnsupportedNode is returned by Render for a node of a kind that has no node renderer func,
+// if the renderer is configured with UnsupportedNodeHandlingError.
+type ErrUnsupportedNode struct {
+	// Kind is the kind of the node
+	Kind ast.NodeKind
+}
+
+// Error implements error.Error
+func (e *ErrUnsupportedNode) Error() string {
+	return fmt.Sprintf("unsupported node kind %s", e.Kind)
+}
+
+// nodeRenderer returns the node renderer func for nodes of the given kind, or nil if there isn't
+// one.
+func (r *Renderer) nodeRenderer(kind ast.NodeKind) nodeRenderer {
+	if int(kind) < 0 || int(kind) >= len(r.nodeRendererFuncs) {
+		return nil
+	}
+	return r.nodeRendererFuncs[kind]
+}
+
+// renderUnsupportedNode renders a node of a kind that has no node renderer func, by skipping it,
+// writing its source as it is, or stopping with an error, according to the configuration.
+func (r *Renderer) renderUnsupportedNode(node ast.Node, entering bool) ast.WalkStatus {
+	switch r.config.UnsupportedNodeHandling {
+	case UnsupportedNodeHandlingSkip:
+		return ast.WalkSkipChildren
+	case UnsupportedNodeHandlingError:
+		r.rc.err = &ErrUnsupportedNode{Kind: node.Kind()}
+		return ast.WalkStop
+	}
+	if node.Type() != ast.TypeInline {
+		return r.chainRenderers(r.renderBlockSeparator, r.renderVerbatimBlock)(node, entering)
+	}
+	if entering {
+		if source, ok := r.inlineSource(node); ok {
+			r.rc.writer.WriteBytes(source)
+			return ast.WalkSkipChildren
+		}
+	}
+	// Without its source, at least the children of the node are rendered
+	return ast.WalkContinue
+}
+
+// renderVerbatimBlock writes the lines of a block as they are. Blocks without lines, like container
+// blocks, have their children rendered instead.
+func (r *Renderer) renderVerbatimBlock(node ast.Node, entering bool) ast.WalkStatus {
+	if node.Lines().Len() == 0 {
+		return ast.WalkContinue
+	}
+	if entering {
+		r.rc.writer.BeginVerbatim()
+		r.renderLines(node, entering)
+		r.rc.writer.EndVerbatim()
+	}
+	return ast.WalkSkipChildren
+}
+
+// inlineSource returns the source of an inline node. Inline nodes don't have segments of their own,
+// so the source is found between the text around the node when there is text on both sides on the
+// same line, which includes any delimiters of the node. Otherwise it is the span of the text in the
+// node, without delimiters.
+func (r *Renderer) inlineSource(node ast.Node) ([]byte, bool) {
+	start, stop, ok := textSpan(node)
+	prev, prevOK := node.PreviousSibling().(*ast.Text)
+	next, nextOK := node.NextSibling().(*ast.Text)
+	if prevOK && nextOK && !prev.SoftLineBreak() && !prev.HardLineBreak() &&
+		prev.Segment.Stop <= next.Segment.Start && next.Segment.Start <= len(r.rc.source) &&
+		bytes.IndexByte(r.rc.source[prev.Segment.Stop:next.Segment.Start], lineDelim) < 0 {
+		start, stop, ok = prev.Segment.Stop, next.Segment.Start, true
+	}
+	if !ok || stop > len(r.rc.source) {
+		return nil, false
+	}
+	return r.rc.source[start:stop], true
+}
+
+// textSpan returns the offsets in the source of the start of the first text in the given node, and
+// the end of the last.
+func textSpan(node ast.Node) (start, stop int, ok bool) {
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if t, isText := n.(*ast.Text); isText && entering {
+			if !ok {
+				start, ok = t.Segment.Start, true
+			}
+			stop = t.Segment.Stop
+		}
+		return ast.WalkContinue, nil
+	})
+	return start, stop, ok && start <= stop
+}
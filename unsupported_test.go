@@ -0,0 +1,114 @@
+package markdown
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/text"
+)
+
+var (
+	kindTestInline = ast.NewNodeKind("TestInline")
+	kindTestBlock  = ast.NewNodeKind("TestBlock")
+)
+
+// testInline and testBlock are nodes of kinds that the renderer has no node renderer funcs for.
+type testInline struct {
+	ast.BaseInline
+}
+
+func (n *testInline) Kind() ast.NodeKind { return kindTestInline }
+
+func (n *testInline) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+type testBlock struct {
+	ast.BaseBlock
+}
+
+func (n *testBlock) Kind() ast.NodeKind { return kindTestBlock }
+
+func (n *testBlock) Dump(source []byte, level int) { ast.DumpHelper(n, source, level, nil, nil) }
+
+// parseUnsupported parses the given source, and replaces its first emphasis and last block with
+// nodes of unsupported kinds.
+func parseUnsupported(md goldmark.Markdown, source []byte) ast.Node {
+	doc := md.Parser().Parse(text.NewReader(source))
+	var emphasis ast.Node
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if entering && n.Kind() == ast.KindEmphasis && emphasis == nil {
+			emphasis = n
+		}
+		return ast.WalkContinue, nil
+	})
+	inline := &testInline{}
+	for c := emphasis.FirstChild(); c != nil; c = emphasis.FirstChild() {
+		inline.AppendChild(inline, c)
+	}
+	emphasis.Parent().ReplaceChild(emphasis.Parent(), emphasis, inline)
+
+	paragraph := doc.LastChild()
+	block := &testBlock{}
+	block.SetLines(paragraph.Lines())
+	block.SetBlankPreviousLines(paragraph.HasBlankPreviousLines())
+	for c := paragraph.FirstChild(); c != nil; c = paragraph.FirstChild() {
+		block.AppendChild(block, c)
+	}
+	doc.ReplaceChild(doc, paragraph, block)
+	return doc
+}
+
+// TestUnsupportedNode tests the rendering of nodes of kinds without node renderer funcs.
+func TestUnsupportedNode(t *testing.T) {
+	source := []byte("foo *bar* baz\n\n> quux\n\n__qux__ *corge*")
+	testCases := []struct {
+		name     string
+		handling UnsupportedNodeHandling
+		expected string
+	}{
+		{"Verbatim", UnsupportedNodeHandlingVerbatim, "foo *bar* baz\n\n> quux\n\n__qux__ *corge*\n"},
+		{"Skip", UnsupportedNodeHandlingSkip, "foo  baz\n\n> quux\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			md := goldmark.New(goldmark.WithExtensions(NewExtension(WithUnsupportedNodeHandling(tc.handling))))
+			doc := parseUnsupported(md, source)
+			buf := bytes.Buffer{}
+			err := md.Renderer().Render(&buf, source, doc)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+// TestUnsupportedNodeError tests that rendering stops with an error at the first node of a kind
+// without a node renderer func.
+func TestUnsupportedNodeError(t *testing.T) {
+	source := []byte("foo *bar* baz\n\nqux")
+	md := goldmark.New(goldmark.WithExtensions(NewExtension(
+		WithUnsupportedNodeHandling(UnsupportedNodeHandlingError))))
+	doc := parseUnsupported(md, source)
+	err := md.Renderer().Render(&bytes.Buffer{}, source, doc)
+
+	var unsupported *ErrUnsupportedNode
+	if assert.True(t, errors.As(err, &unsupported)) {
+		assert.Equal(t, kindTestInline, unsupported.Kind)
+		assert.Equal(t, "unsupported node kind TestInline", err.Error())
+	}
+}
+
+// TestNilNodeRendererFunc tests that registering a nil node renderer func leaves the kind
+// unsupported.
+func TestNilNodeRendererFunc(t *testing.T) {
+	source := []byte("foo *bar* baz\n\nqux")
+	r := NewRenderer()
+	r.Register(ast.KindEmphasis, nil)
+	md := goldmark.New(goldmark.WithRenderer(r))
+	buf := bytes.Buffer{}
+	err := md.Convert(source, &buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo *bar* baz\n\nqux\n", buf.String())
+}
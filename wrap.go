@@ -0,0 +1,165 @@
+package markdown
+
+import (
+	"bytes"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+// wrapProse reflows prose by breaking lines at the spaces whose offsets in text are given in
+// breaks, so that each line fits within the number of columns returned by available where possible.
+// available is called with the number of the line relative to the first line of the prose. Line
+// breaks already in text, such as hard line breaks, are kept.
+func wrapProse(text []byte, breaks []int, available func(line int) int) []byte {
+	if looksLikeDefinition(text) {
+		return text
+	}
+	result := make([]byte, 0, len(text))
+	line, column := 0, 0
+	start := 0
+	for i := 0; i <= len(breaks); i++ {
+		end := len(text)
+		if i < len(breaks) {
+			end = breaks[i]
+		}
+		word := text[start:end]
+		if start > 0 {
+			// Replace the space before the word with a line break if the word doesn't fit
+			first := word
+			if j := bytes.IndexByte(word, lineDelim); j >= 0 {
+				first = word[:j]
+			}
+			width := displayWidth(first)
+			if column > 0 && len(first) > 0 && column+1+width > available(line) &&
+				canBreakBefore(first, result) {
+				// Spaces at the end of a line would be written as a hard line break
+				result = append(bytes.TrimRight(result, " "), lineDelim)
+				line++
+				column = 0
+			} else {
+				result = append(result, ' ')
+				column++
+			}
+		}
+		result = append(result, word...)
+		if j := bytes.LastIndexByte(word, lineDelim); j >= 0 {
+			line += bytes.Count(word, []byte{lineDelim})
+			column = displayWidth(word[j+1:])
+		} else {
+			column += displayWidth(word)
+		}
+		start = end + 1
+	}
+	return result
+}
+
+// canBreakBefore returns true if a line can be broken between the given word and the text before
+// it without changing the meaning of either.
+func canBreakBefore(word, before []byte) bool {
+	// A backslash at the end of a line is a hard line break
+	if len(before) > 0 && before[len(before)-1] == '\\' {
+		return false
+	}
+	// The next word will start a line, so it must not start a block that can interrupt a paragraph.
+	// The word is checked both by itself and followed by more text, since some blocks, like list
+	// items, require content and others, like setext heading underlines, can't have any.
+	alone := textContext{lineStart: true, continuation: true, lineEnd: true}
+	followed := textContext{lineStart: true, continuation: true}
+	if !bytes.Equal(escapeLineStart(word, alone), word) {
+		return false
+	}
+	if withText := append(word[:len(word):len(word)], " x"...); !bytes.Equal(escapeLineStart(withText, followed), withText) {
+		return false
+	}
+	// Raw HTML at the start of a line can start an HTML block, and a colon can start a definition
+	// list description.
+	return word[0] != '<' && !bytes.Equal(word, []byte(":"))
+}
+
+// looksLikeDefinition returns true if text could be turned into a link reference definition by
+// breaking its lines differently.
+func looksLikeDefinition(text []byte) bool {
+	return bytes.HasPrefix(text, []byte("[")) && bytes.Contains(text, []byte("]:"))
+}
+
+// breakSentences starts each sentence in prose on a new line by replacing the spaces whose offsets
+// in text are given in breaks with line breaks after sentences, and keeping the rest as spaces.
+// Line breaks already in text, such as hard line breaks, are kept.
+func breakSentences(text []byte, breaks []int) []byte {
+	if looksLikeDefinition(text) {
+		return text
+	}
+	result := make([]byte, 0, len(text))
+	start := 0
+	for i := 0; i <= len(breaks); i++ {
+		end := len(text)
+		if i < len(breaks) {
+			end = breaks[i]
+		}
+		word := text[start:end]
+		if start > 0 {
+			// The previous word is the text since the last line break or breakable space
+			previous := result[bytes.LastIndexAny(result, " \n")+1:]
+			next := word
+			if j := bytes.IndexByte(word, lineDelim); j >= 0 {
+				next = word[:j]
+			}
+			if len(next) > 0 && isSentenceEnd(previous) && isSentenceStart(next) && canBreakBefore(next, result) {
+				result = append(bytes.TrimRight(result, " "), lineDelim)
+			} else {
+				result = append(result, ' ')
+			}
+		}
+		result = append(result, word...)
+		start = end + 1
+	}
+	return result
+}
+
+// abbreviations holds common abbreviations that end with a period, which usually don't end a
+// sentence.
+var abbreviations = map[string]bool{
+	"approx.": true, "cf.": true, "co.": true, "corp.": true, "dept.": true, "dr.": true,
+	"est.": true, "fig.": true, "inc.": true, "jr.": true, "ltd.": true, "mr.": true, "mrs.": true,
+	"ms.": true, "mt.": true, "no.": true, "nos.": true, "p.": true, "pp.": true, "prof.": true,
+	"sr.": true, "st.": true, "vol.": true, "vs.": true,
+}
+
+// initialism matches words with periods between letters, such as "e.g." or "U.S.", and single
+// letter initials such as "J.".
+var initialism = regexp.MustCompile(`^(\pL\.)+$`)
+
+// sentenceOpeners and sentenceClosers are the punctuation and markup characters that can surround
+// the words at the start and end of a sentence.
+const (
+	sentenceOpeners = "\"'([*_"
+	sentenceClosers = "\"')]*_"
+)
+
+// isSentenceEnd returns true if word ends a sentence. Periods in abbreviations and initialisms
+// don't end sentences, and neither does punctuation inside code spans, as it is followed by a
+// backtick.
+func isSentenceEnd(word []byte) bool {
+	word = bytes.TrimRight(word, sentenceClosers)
+	if len(word) == 0 {
+		return false
+	}
+	switch word[len(word)-1] {
+	case '!', '?':
+		return true
+	case '.':
+		word = bytes.TrimLeft(word, sentenceOpeners)
+		return !abbreviations[string(bytes.ToLower(word))] && !initialism.Match(word)
+	}
+	return false
+}
+
+// isSentenceStart returns true if word can start a sentence, i.e. it starts with an uppercase
+// letter or a digit. A lowercase word after a period usually means the period was part of an
+// abbreviation or a number, as in "approx. 3.5 in. long".
+func isSentenceStart(word []byte) bool {
+	word = bytes.TrimLeft(word, sentenceOpeners)
+	r, _ := utf8.DecodeRune(word)
+	return unicode.IsUpper(r) || unicode.IsDigit(r)
+}
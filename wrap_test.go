@@ -0,0 +1,133 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yuin/goldmark"
+)
+
+// proseBreaks returns the offsets of the spaces in text outside of backticks.
+func proseBreaks(text string) []int {
+	breaks := []int{}
+	code := false
+	for i, c := range []byte(text) {
+		if c == '`' {
+			code = !code
+		} else if c == ' ' && !code {
+			breaks = append(breaks, i)
+		}
+	}
+	return breaks
+}
+
+// TestWrapProse tests that prose is wrapped only at breakable spaces.
+func TestWrapProse(t *testing.T) {
+	width := func(int) int { return 10 }
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Short", "foo bar", "foo bar"},
+		{"Wrapped", "foo bar baz qux quux", "foo bar\nbaz qux\nquux"},
+		{"Long word", "foo abcdefghijklmn bar", "foo\nabcdefghijklmn\nbar"},
+		{"Unbreakable spaces", "foo `bar baz qux` quux", "foo\n`bar baz qux`\nquux"},
+		{"Hard line break", "foo bar\\\nbaz qux quux", "foo bar\\\nbaz qux\nquux"},
+		{"Spaces hard line break", "foo bar  \nbaz qux quux", "foo bar  \nbaz qux\nquux"},
+		{"Repeated spaces", "foo bar   bazqux", "foo bar\nbazqux"},
+		{"Block marker", "foo barbaz - qux", "foo barbaz -\nqux"},
+		{"Trailing backslash", "foo bar\\ baz", "foo bar\\ baz"},
+		{"Definition", "[foo]: /url 'title' ok", "[foo]: /url 'title' ok"},
+		{"Wide characters", "日本 語 ｆｕ ｌ", "日本 語\nｆｕ ｌ"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := wrapProse([]byte(tc.text), proseBreaks(tc.text), width)
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
+
+// TestWrapProseDisplayWidth tests that prose with East Asian wide and fullwidth characters is wrapped
+// so that no line is wider than the line width, including the prefixes of the blocks around it.
+func TestWrapProseDisplayWidth(t *testing.T) {
+	const lineWidth = 14
+	testCases := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"Paragraph", "日本語 日本語 日本語", "日本語 日本語\n日本語\n"},
+		{"Fullwidth", "ｆｏｏ ｂａｒ ｂａｚ", "ｆｏｏ ｂａｒ\nｂａｚ\n"},
+		{"Blockquote", "> 日本語 日本語 日本語", "> 日本語\n> 日本語\n> 日本語\n"},
+		{"List item", "- 日本 語 日本語 ｆｏｏ", "- 日本 語\n  日本語\n  ｆｏｏ\n"},
+	}
+	md := goldmark.New(goldmark.WithRenderer(NewRenderer(WithProseWrap(ProseWrapAlways), WithLineWidth(lineWidth))))
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := bytes.Buffer{}
+			err := md.Convert([]byte(tc.source), &buf)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, buf.String())
+			for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+				assert.LessOrEqual(t, displayWidth([]byte(line)), lineWidth, "line %q", line)
+			}
+		})
+	}
+}
+
+// TestCanBreakBefore tests that lines are not broken before words that would start a block.
+func TestCanBreakBefore(t *testing.T) {
+	testCases := []struct {
+		word     string
+		expected bool
+	}{
+		{"foo", true},
+		{"-", false},
+		{"+", false},
+		{"-foo", true},
+		{"#", false},
+		{"#foo", true},
+		{">", false},
+		{"1.", false},
+		{"2.", true},
+		{"***", false},
+		{"===", false},
+		{"```", false},
+		{"<div>", false},
+		{":", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.word, func(t *testing.T) {
+			assert.Equal(t, tc.expected, canBreakBefore([]byte(tc.word), []byte("foo")))
+		})
+	}
+	assert.False(t, canBreakBefore([]byte("foo"), []byte("foo\\")))
+}
+
+// TestBreakSentences tests that each sentence starts on a new line.
+func TestBreakSentences(t *testing.T) {
+	testCases := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"Sentences", "One. Two! Three? four", "One.\nTwo!\nThree? four"},
+		{"Abbreviations", "Dr. Smith vs. Mr. Jones. Done", "Dr. Smith vs. Mr. Jones.\nDone"},
+		{"Initialisms", "See e.g. The U.S. Army or J. Doe", "See e.g. The U.S. Army or J. Doe"},
+		{"Decimals", "About 3. 5 is 3.5. Next", "About 3.\n5 is 3.5.\nNext"},
+		{"Code span", "Run `go test. Then` Now", "Run `go test. Then` Now"},
+		{"Closing punctuation", "(One.) **Two.** \"Three.\"", "(One.)\n**Two.**\n\"Three.\""},
+		{"Hard line break", "One\\\nTwo. Three", "One\\\nTwo.\nThree"},
+		{"Block marker", "One. 1. Two", "One. 1.\nTwo"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			actual := breakSentences([]byte(tc.text), proseBreaks(tc.text))
+			assert.Equal(t, tc.expected, string(actual))
+		})
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark/util"
 )
@@ -17,6 +18,43 @@ type linePrefix struct {
 	startLine, endLine int
 	// bytes is the bytes of the prefix
 	bytes []byte
+	// indent is the width in columns of the whitespace to write for the prefix, if bytes is nil
+	indent int
+}
+
+// A Writer is the util.BufWriter passed to node renderer funcs by the Renderer. Node renderer funcs
+// registered with Register or RegisterWrapper can type-assert their writer to a Writer to render
+// container blocks, whose lines are prefixed with markers or indentation.
+//
+// Lines are buffered until they are ended, and then written with the prefixes that apply to them.
+// Trailing whitespace is trimmed from lines, except for lines written in verbatim regions.
+type Writer interface {
+	util.BufWriter
+	// PushPrefix adds the given bytes as a prefix for lines written from the current line on. The
+	// prefix can optionally be given a start line relative to the current line, and an end line
+	// relative to the start line.
+	PushPrefix(prefix []byte, lineRanges ...int)
+	// PushIndent adds indentation of the given width in columns as a prefix for lines, with the
+	// same line ranges as PushPrefix. The indentation is written with the configured IndentStyle.
+	PushIndent(width int, lineRanges ...int)
+	// PopPrefix removes the most recently pushed prefix.
+	PopPrefix()
+	// WriteLine writes the given bytes and ends the line, unless the bytes are empty and the line
+	// is too.
+	WriteLine(line []byte) int
+	// FlushLine ends the current line if anything has been written to it.
+	FlushLine()
+	// EndLine ends the current line, writing an empty line if nothing has been written to it.
+	EndLine()
+	// BeginVerbatim starts a region of lines whose trailing whitespace is kept, and EndVerbatim
+	// ends it.
+	BeginVerbatim()
+	EndVerbatim()
+	// Column returns the column of the end of the current line, including its prefixes, starting at
+	// 0. Tabs advance to the next multiple of four columns.
+	Column() int
+	// Line returns the number of the current line, starting at 0.
+	Line() int
 }
 
 // markdownWriter provides an interface similar to io.Writer for writing markdown files. It handles
@@ -31,17 +69,25 @@ type markdownWriter struct {
 	prefixes []linePrefix
 	// line is the current line number
 	line int
+	// verbatim is the number of verbatim regions being written. Trailing whitespace is kept on
+	// lines written in verbatim regions.
+	verbatim int
+	// lineEnding is written at the end of each line in place of lineDelim
+	lineEnding []byte
+	// blankLines is the number of blank lines at the end of the output
+	blankLines int
 	// err holds the last write error. If non-nil, all write operations become no-ops
 	err error
 }
 
-var _ util.BufWriter = &markdownWriter{}
+var _ Writer = &markdownWriter{}
 
 // newMarkdownWriter returns a new markdownWriter
 func newMarkdownWriter(w io.Writer, config *Config) *markdownWriter {
 	result := &markdownWriter{
-		config: config,
-		buf:    &bytes.Buffer{},
+		config:     config,
+		buf:        &bytes.Buffer{},
+		lineEnding: []byte{lineDelim},
 	}
 	// Reset initializes the rest of the struct
 	result.Reset(w)
@@ -54,6 +100,8 @@ func (m *markdownWriter) Reset(w io.Writer) {
 	m.output = w
 	m.prefixes = make([]linePrefix, 0)
 	m.line = 0
+	m.verbatim = 0
+	m.blankLines = 0
 	m.err = nil
 }
 
@@ -78,6 +126,17 @@ func (m *markdownWriter) EndLine() {
 	_, _ = m.Write([]byte{lineDelim})
 }
 
+// BeginVerbatim starts a region of lines that are written as they are. Trailing whitespace is only
+// trimmed from the prefixes of empty lines, which would otherwise be left with trailing whitespace.
+func (m *markdownWriter) BeginVerbatim() {
+	m.verbatim++
+}
+
+// EndVerbatim ends the verbatim region started by the last call to BeginVerbatim.
+func (m *markdownWriter) EndVerbatim() {
+	m.verbatim--
+}
+
 // PushPrefix adds the given bytes as a prefix for lines written to the output. The prefix
 // will be added to the current line and all subsequent lines by default, but can optionally be
 // given a start line relative to the current line, and an end line relative to the start line.
@@ -95,6 +154,15 @@ func (p *markdownWriter) PushPrefix(bytes []byte, lineRanges ...int) {
 	p.prefixes = append(p.prefixes, prefix)
 }
 
+// PushIndent adds indentation of the given width in columns as a prefix for lines written to the
+// output, with the same line ranges as PushPrefix. The indentation is written with the configured
+// IndentStyle: with tabs, a tab is written for each tab stop reached from the column the indentation
+// starts at, and spaces are written for the rest.
+func (p *markdownWriter) PushIndent(width int, lineRanges ...int) {
+	p.PushPrefix(nil, lineRanges...)
+	p.prefixes[len(p.prefixes)-1].indent = width
+}
+
 // PopPrefix removes the most recently pushed line prefix from future lines.
 func (p *markdownWriter) PopPrefix() {
 	p.prefixes = p.prefixes[0 : len(p.prefixes)-1]
@@ -116,17 +184,23 @@ func (m *markdownWriter) WriteBytes(data []byte) (n int) {
 	for bytes.Contains(m.buf.Bytes(), []byte{lineDelim}) {
 		// err will only be non-nil if lineDelim is not in m.buf, which we already checked for.
 		line, _ := m.buf.ReadBytes(lineDelim)
-		// build the prefix for the line
-		for _, prefix := range m.prefixes {
-			if prefix.startLine <= m.line && (prefix.endLine == -1 || m.line <= prefix.endLine) {
-				prefixedLine.Write(prefix.bytes)
-			}
-		}
+		prefixedLine.Write(m.linePrefix(0))
 		prefixedLine.Write(line)
-		// trim whitespace off the end of the line
-		trimmedSlice := bytes.TrimRightFunc(prefixedLine.Bytes(), unicode.IsSpace)
-		prefixedLine.Truncate(len(trimmedSlice))
-		prefixedLine.WriteByte(lineDelim)
+		// trim whitespace off the end of the line, except for the contents of verbatim lines, which
+		// only have their line ending replaced
+		content := bytes.TrimSuffix(line[:len(line)-1], []byte{'\r'})
+		if m.verbatim == 0 || len(content) == 0 {
+			trimmedSlice := bytes.TrimRightFunc(prefixedLine.Bytes(), unicode.IsSpace)
+			prefixedLine.Truncate(len(trimmedSlice))
+		} else {
+			prefixedLine.Truncate(prefixedLine.Len() - (len(line) - len(content)))
+		}
+		if prefixedLine.Len() == 0 {
+			m.blankLines++
+		} else {
+			m.blankLines = 0
+		}
+		prefixedLine.Write(m.lineEnding)
 
 		_, err := m.output.Write(prefixedLine.Bytes())
 		if err != nil {
@@ -139,6 +213,76 @@ func (m *markdownWriter) WriteBytes(data []byte) (n int) {
 	return n
 }
 
+// lastRune returns the last rune written to the current line, or lineDelim if the line is empty.
+func (m *markdownWriter) lastRune() rune {
+	r, size := utf8.DecodeLastRune(m.buf.Bytes())
+	if size == 0 {
+		return rune(lineDelim)
+	}
+	return r
+}
+
+// linePrefix returns the prefixes of the line offset lines after the current line.
+func (m *markdownWriter) linePrefix(offset int) []byte {
+	line := m.line + offset
+	var result []byte
+	column := 0
+	for _, prefix := range m.prefixes {
+		if prefix.startLine > line || (prefix.endLine != -1 && line > prefix.endLine) {
+			continue
+		}
+		if prefix.bytes != nil {
+			result = append(result, prefix.bytes...)
+			column += columnWidth(prefix.bytes, column)
+			continue
+		}
+		end := column + prefix.indent
+		if m.config.IndentStyle == IndentStyleTabs {
+			for next := column + tabWidth - column%tabWidth; next <= end; next += tabWidth {
+				result = append(result, '\t')
+				column = next
+			}
+		}
+		result = append(result, bytes.Repeat([]byte{' '}, end-column)...)
+		column = end
+	}
+	return result
+}
+
+// prefixWidth returns the width in columns of the prefixes of the line offset lines after the
+// current line.
+func (m *markdownWriter) prefixWidth(offset int) int {
+	return columnWidth(m.linePrefix(offset), 0)
+}
+
+// tabWidth is the number of columns between tab stops.
+const tabWidth = 4
+
+// columnWidth returns the width in columns of text written starting at the given column. Tabs advance to
+// the next tab stop.
+func columnWidth(text []byte, column int) int {
+	start := column
+	for _, r := range string(text) {
+		if r == '\t' {
+			column += tabWidth - column%tabWidth
+		} else {
+			column++
+		}
+	}
+	return column - start
+}
+
+// Column returns the column of the end of the current line, including its prefixes.
+func (m *markdownWriter) Column() int {
+	prefix := columnWidth(m.linePrefix(0), 0)
+	return prefix + columnWidth(m.buf.Bytes(), prefix)
+}
+
+// Line returns the number of the current line, starting at 0.
+func (m *markdownWriter) Line() int {
+	return m.line
+}
+
 // Err returns the last write error, or nil.
 func (m *markdownWriter) Err() error {
 	return m.err
@@ -157,17 +301,98 @@ func (m *markdownWriter) Buffered() int {
 // Flush flushes the contents of the buffer to the output.
 func (m *markdownWriter) Flush() error {
 	m.FlushLine()
-	return nil
+	return m.err
 }
 
+// WriteByte writes a single byte, as Write does.
 func (m *markdownWriter) WriteByte(c byte) error {
-	return m.buf.WriteByte(c)
+	_, err := m.Write([]byte{c})
+	return err
 }
 
+// WriteRune writes the UTF-8 encoding of a rune, as Write does.
 func (m *markdownWriter) WriteRune(r rune) (size int, err error) {
-	return m.buf.WriteRune(r)
+	return m.Write(utf8.AppendRune(nil, r))
 }
 
+// WriteString writes the contents of a string, as Write does.
 func (m *markdownWriter) WriteString(s string) (n int, err error) {
-	return m.buf.WriteString(s)
+	return m.Write([]byte(s))
+}
+
+// lineEnding returns the line ending to write for the given style, detecting the line ending of the
+// source for LineEndingDetect.
+func lineEnding(style LineEnding, source []byte) []byte {
+	switch style {
+	case LineEndingCRLF:
+		return []byte("\r\n")
+	case LineEndingDetect:
+		if i := bytes.IndexByte(source, lineDelim); i > 0 && source[i-1] == '\r' {
+			return []byte("\r\n")
+		}
+	}
+	return []byte{lineDelim}
+}
+
+// byteOrderMark is the UTF-8 encoding of the byte order mark.
+var byteOrderMark = []byte("\uFEFF")
+
+// documentWriter writes the lines written by a markdownWriter for a whole document to an output
+// writer. It starts the document with a byte order mark if needed, and holds back the line endings
+// of the last line and any blank lines after it until they are known to be followed by more text,
+// so the end of the document can be ended as configured.
+type documentWriter struct {
+	output io.Writer
+	// bom is true if the document starts with a byte order mark
+	bom bool
+	// finalNewline is true if the document ends with a line ending
+	finalNewline bool
+	// started is true once anything has been written to output
+	started bool
+	// ending holds the line ending of the last line, and pending holds it followed by the blank
+	// lines written after that line
+	ending, pending []byte
+}
+
+// newDocumentWriter returns a new documentWriter for rendering the given source to w.
+func newDocumentWriter(w io.Writer, source []byte, config *Config) *documentWriter {
+	return &documentWriter{
+		output:       w,
+		bom:          bytes.HasPrefix(source, byteOrderMark) && config.ByteOrderMark == ByteOrderMarkPreserve,
+		finalNewline: config.FinalNewline == FinalNewlineEnsure || bytes.HasSuffix(source, []byte{lineDelim}),
+	}
+}
+
+// Write writes a line ended by a line ending, which is held back with any blank lines that follow
+// it until more text is written.
+func (d *documentWriter) Write(line []byte) (int, error) {
+	content := bytes.TrimRight(line, "\r\n")
+	if len(content) == 0 {
+		if d.started {
+			d.pending = append(d.pending, line...)
+		}
+		return len(line), nil
+	}
+	if !d.started && d.bom {
+		d.pending = append(d.pending, byteOrderMark...)
+	}
+	d.started = true
+	if _, err := d.output.Write(append(d.pending, content...)); err != nil {
+		return 0, err
+	}
+	d.ending = append(d.ending[:0], line[len(content):]...)
+	d.pending = append(d.pending[:0], d.ending...)
+	return len(line), nil
+}
+
+// Close ends the document, writing the line ending of the last line if configured to. Empty
+// documents are left empty, apart from their byte order mark.
+func (d *documentWriter) Close() error {
+	var err error
+	if !d.started && d.bom {
+		_, err = d.output.Write(byteOrderMark)
+	} else if d.started && d.finalNewline {
+		_, err = d.output.Write(d.ending)
+	}
+	return err
 }
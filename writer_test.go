@@ -8,6 +8,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/yuin/goldmark/util"
 )
 
 func TestWrite(t *testing.T) {
@@ -177,6 +178,206 @@ func (e *errorWriter) Write([]byte) (int, error) {
 	return 0, e.err
 }
 
+// writeMethods holds a function for each method of util.BufWriter that writes text, which writes
+// the given text with that method.
+var writeMethods = []struct {
+	name  string
+	write func(w util.BufWriter, text string) error
+}{
+	{"Write", func(w util.BufWriter, text string) error {
+		_, err := w.Write([]byte(text))
+		return err
+	}},
+	{"WriteByte", func(w util.BufWriter, text string) error {
+		for i := 0; i < len(text); i++ {
+			if err := w.WriteByte(text[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"WriteRune", func(w util.BufWriter, text string) error {
+		for _, r := range text {
+			if _, err := w.WriteRune(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}},
+	{"WriteString", func(w util.BufWriter, text string) error {
+		_, err := w.WriteString(text)
+		return err
+	}},
+}
+
+// TestWriteMethods tests that all write methods process lines the same way, writing prefixes,
+// trimming trailing whitespace and ending lines with the configured line ending.
+func TestWriteMethods(t *testing.T) {
+	testCases := []struct {
+		name     string
+		prefix   func(w *markdownWriter)
+		expected string
+	}{
+		{"No prefix", func(w *markdownWriter) {}, "föö\r\n\r\nbar\r\n"},
+		{"Blockquote", func(w *markdownWriter) { w.PushPrefix([]byte("> ")) }, "> föö\r\n>\r\n> bar\r\n"},
+		{
+			"List item",
+			func(w *markdownWriter) {
+				w.PushPrefix([]byte("- "), 0, 0)
+				w.PushIndent(2, 1)
+			},
+			"- föö\r\n\r\n  bar\r\n",
+		},
+		{
+			"List item in blockquote",
+			func(w *markdownWriter) {
+				w.PushPrefix([]byte("> "))
+				w.PushPrefix([]byte("1. "), 0, 0)
+				w.PushIndent(3, 1)
+			},
+			"> 1. föö\r\n>\r\n>    bar\r\n",
+		},
+	}
+	for _, tc := range testCases {
+		for _, method := range writeMethods {
+			t.Run(tc.name+"/"+method.name, func(t *testing.T) {
+				buf := bytes.Buffer{}
+				writer := newMarkdownWriter(&buf, NewConfig())
+				writer.lineEnding = []byte("\r\n")
+				tc.prefix(writer)
+				require.NoError(t, method.write(writer, "föö \n\nbar\t"))
+				lastLine := strings.LastIndex(strings.TrimSuffix(tc.expected, "\r\n"), "\r\n") + 2
+				assert.Equal(t, tc.expected[:lastLine], buf.String(), "Only complete lines should be written")
+				require.NoError(t, writer.Flush())
+				assert.Equal(t, tc.expected, buf.String())
+			})
+		}
+	}
+}
+
+// TestWriteMethodsError tests that all write methods return errors from the output.
+func TestWriteMethodsError(t *testing.T) {
+	for _, method := range writeMethods {
+		t.Run(method.name, func(t *testing.T) {
+			err := fmt.Errorf("test error")
+			writer := newMarkdownWriter(&errorWriter{err: err}, NewConfig())
+			assert.Equal(t, err, method.write(writer, "foo\nbar"))
+			assert.Equal(t, err, writer.Flush())
+		})
+	}
+}
+
+// TestPrefixWidth tests that the width of line prefixes accounts for their line ranges and tabs.
+func TestPrefixWidth(t *testing.T) {
+	assert := assert.New(t)
+	writer := newMarkdownWriter(&bytes.Buffer{}, NewConfig())
+
+	assert.Equal(0, writer.prefixWidth(0))
+	writer.PushPrefix([]byte("> "))
+	writer.PushPrefix([]byte("10. "), 0, 0)
+	writer.PushPrefix([]byte("    "), 1)
+	assert.Equal(6, writer.prefixWidth(0))
+	assert.Equal(6, writer.prefixWidth(1))
+	writer.PushPrefix([]byte("\t"))
+	assert.Equal(8, writer.prefixWidth(0))
+	writer.WriteLine([]byte("foo"))
+	assert.Equal(8, writer.prefixWidth(0))
+}
+
+// TestColumnAndLine tests that the writer reports the position of the end of the current line,
+// including its prefixes.
+func TestColumnAndLine(t *testing.T) {
+	assert := assert.New(t)
+	writer := newMarkdownWriter(&bytes.Buffer{}, NewConfig())
+
+	assert.Equal(0, writer.Column())
+	assert.Equal(0, writer.Line())
+	writer.PushPrefix([]byte("> "))
+	writer.PushPrefix([]byte("- "), 0, 0)
+	writer.PushIndent(2, 1)
+	assert.Equal(4, writer.Column())
+	_, _ = writer.Write([]byte("foo"))
+	assert.Equal(7, writer.Column())
+	assert.Equal(0, writer.Line())
+	_, _ = writer.Write([]byte("\n\tbar"))
+	assert.Equal(11, writer.Column())
+	assert.Equal(1, writer.Line())
+	writer.EndLine()
+	assert.Equal(4, writer.Column())
+	assert.Equal(2, writer.Line())
+}
+
+// TestIndent tests that indentation prefixes are written with the indent style, with tabs written
+// for the tab stops the indentation reaches from where it starts.
+func TestIndent(t *testing.T) {
+	testCases := []struct {
+		name     string
+		style    IndentStyle
+		expected string
+	}{
+		{"Spaces", IndentStyleSpaces, "- foo\n      bar\n\n  > baz\n  >     qux\n"},
+		{"Tabs", IndentStyleTabs, "- foo\n  \t  bar\n\n  > baz\n  > \tqux\n"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			config := NewConfig(WithIndentStyle(tc.style))
+			writer := newMarkdownWriter(buf, config)
+
+			writer.PushPrefix([]byte("- "), 0, 0)
+			writer.PushIndent(2, 1)
+			writer.WriteLine([]byte("foo"))
+			writer.PushIndent(4)
+			writer.WriteLine([]byte("bar"))
+			writer.PopPrefix()
+			writer.EndLine()
+			writer.PushPrefix([]byte("> "))
+			writer.WriteLine([]byte("baz"))
+			writer.PushIndent(4)
+			writer.WriteLine([]byte("qux"))
+			assert.Equal(t, tc.expected, buf.String())
+			assert.Equal(t, 8, writer.prefixWidth(0))
+		})
+	}
+}
+
+// TestVerbatim tests that trailing whitespace is kept on lines written in verbatim regions.
+func TestVerbatim(t *testing.T) {
+	assert := assert.New(t)
+	buf := &bytes.Buffer{}
+	writer := newMarkdownWriter(buf, NewConfig())
+
+	writer.PushPrefix([]byte("> "))
+	writer.WriteLine([]byte("foo  "))
+	writer.BeginVerbatim()
+	writer.WriteLine([]byte("bar  "))
+	writer.WriteLine([]byte("  "))
+	writer.EndLine()
+	writer.EndVerbatim()
+	writer.WriteLine([]byte("baz  "))
+	assert.Equal("> foo\n> bar  \n>   \n>\n> baz\n", buf.String())
+}
+
+// TestDocumentWriter tests that the document writer only writes blank lines and line endings that
+// are followed by more text, until the document is closed.
+func TestDocumentWriter(t *testing.T) {
+	assert := assert.New(t)
+	buf := &bytes.Buffer{}
+	config := NewConfig(WithFinalNewline(FinalNewlinePreserve))
+	writer := newMarkdownWriter(newDocumentWriter(buf, []byte("\uFEFFfoo\n"), config), config)
+	writer.lineEnding = []byte("\r\n")
+
+	writer.EndLine()
+	writer.WriteLine([]byte("foo"))
+	writer.EndLine()
+	assert.Equal("\uFEFFfoo", buf.String())
+	writer.WriteLine([]byte("bar"))
+	writer.EndLine()
+	assert.Equal("\uFEFFfoo\r\n\r\nbar", buf.String())
+	assert.NoError(writer.output.(*documentWriter).Close())
+	assert.Equal("\uFEFFfoo\r\n\r\nbar\r\n", buf.String())
+}
+
 // TestWriteError tests that the writer will turn all write operations into no-ops if the output
 // writer returns an error
 func TestWriteError(t *testing.T) {